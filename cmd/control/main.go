@@ -11,7 +11,9 @@ import (
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/config"
 	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/store"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 func main() {
@@ -28,11 +30,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.ControlAdminToken == "" {
+		logr.Warn("CONTROL_ADMIN_TOKEN not set; only admin tokens stored in the database are accepted")
+	}
+
 	ctrlStore := ctl.NewStore(db)
+	webhookStore := webhook.NewStore(db)
+	routerEngine := routing.NewEngine(logr, ctrlStore)
 
-	app := control.NewApp(cfg, logr, ctrlStore)
+	app := control.NewApp(cfg, logr, ctrlStore, webhookStore, routerEngine)
 
-	if err := run(app, logr); err != nil {
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
+	go routerEngine.Run(refreshCtx, cfg.RouteRefreshInterval)
+
+	err = run(app, logr)
+	stopRefresh()
+	if err != nil {
 		logr.Error("control service exited with error", "err", err)
 		os.Exit(1)
 	}
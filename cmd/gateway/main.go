@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,8 +14,12 @@ import (
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/redact"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/store"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 func main() {
@@ -30,8 +36,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cfg.ClientTokenSecret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			logr.Error("failed to generate client token secret", "err", err)
+			os.Exit(1)
+		}
+		cfg.ClientTokenSecret = config.Secret(hex.EncodeToString(b))
+		logr.Warn("CLIENT_TOKEN_SECRET not set; client tokens will not survive a restart or work across gateways")
+	}
+
 	ctrlStore := control.NewStore(db)
-	routerEngine := routing.NewEngine(ctrlStore)
+	routerEngine := routing.NewEngine(logr, ctrlStore)
 
 	logr.Info("starting nexus gateway",
 		"listen_addr", cfg.ListenAddr,
@@ -39,11 +55,43 @@ func main() {
 		"db", cfg.DBDSN,
 	)
 
-	eventService := events.NewLogService(logr)
+	eventService := events.NewSQLService(db, logr, cfg.IdempotencyWindow)
+
+	sequencer := realtime.NewSQLSequencer(db)
+	history := realtime.NewSQLHistory(db, cfg.ChannelHistoryRetention)
+	webhookStore := webhook.NewStore(db)
+	redactor := redact.NewRedactor(ctrlStore, cfg.RedactionRefreshInterval)
+	schemas := schema.NewRegistry(ctrlStore, cfg.SchemaRefreshInterval)
+	keyUsage := control.NewKeyUsage(logr, ctrlStore)
+
+	app := gateway.NewApp(cfg, logr, eventService, ctrlStore, routerEngine, sequencer, history, webhookStore, redactor, schemas, keyUsage)
+
+	dispatcher := webhook.NewDispatcher(webhook.DispatcherConfig{
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		Timeout:      cfg.WebhookTimeout,
+		BackoffBase:  cfg.WebhookBackoffBase,
+		BackoffMax:   cfg.WebhookBackoffMax,
+		PollInterval: cfg.WebhookPollInterval,
 
-	app := gateway.NewApp(cfg, logr, eventService, ctrlStore, routerEngine)
+		Workers:        cfg.WebhookWorkers,
+		MaxPerEndpoint: cfg.WebhookMaxPerEndpoint,
 
-	if err := run(app, logr); err != nil {
+		AllowPrivateAddresses: cfg.WebhookAllowPrivateAddresses,
+	}, logr, webhookStore, ctrlStore)
+
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	go dispatcher.Run(bgCtx)
+	go routerEngine.Run(bgCtx, cfg.RouteRefreshInterval)
+	usageDone := make(chan struct{})
+	go func() {
+		keyUsage.Run(bgCtx, cfg.APIKeyUsageFlushInterval)
+		close(usageDone)
+	}()
+
+	err = run(app, logr)
+	stopBackground()
+	<-usageDone
+	if err != nil {
 		logr.Error("gateway exited with error", "err", err)
 		os.Exit(1)
 	}
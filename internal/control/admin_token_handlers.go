@@ -0,0 +1,126 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+)
+
+type createAdminTokenRequest struct {
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	TenantID string `json:"tenant_id"`
+}
+
+// adminTokenResponse carries the secret only in the create response.
+type adminTokenResponse struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Role      string  `json:"role"`
+	TenantID  string  `json:"tenant_id,omitempty"`
+	Prefix    string  `json:"prefix"`
+	Secret    string  `json:"secret,omitempty"`
+	CreatedAt string  `json:"created_at"`
+	RevokedAt *string `json:"revoked_at,omitempty"`
+}
+
+func toAdminTokenResponse(t ctl.AdminToken) adminTokenResponse {
+	resp := adminTokenResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Role:      t.Role,
+		TenantID:  t.TenantID,
+		Prefix:    t.Prefix,
+		Secret:    t.Secret,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+	}
+	if t.RevokedAt != nil {
+		revoked := t.RevokedAt.Format(time.RFC3339)
+		resp.RevokedAt = &revoked
+	}
+	return resp
+}
+
+func (h *Handler) ListAdminTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.store.ListAdminTokens(r.Context())
+	if err != nil {
+		h.log.Error("list admin tokens failed", "err", err)
+		http.Error(w, "list admin tokens failed", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]adminTokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, toAdminTokenResponse(t))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) CreateAdminToken(w http.ResponseWriter, r *http.Request) {
+	var req createAdminTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if req.TenantID != "" {
+		t, err := h.store.GetTenant(ctx, req.TenantID)
+		if err != nil {
+			h.log.Error("get tenant failed", "err", err)
+			http.Error(w, "create admin token failed", http.StatusInternalServerError)
+			return
+		}
+		if t == nil {
+			http.Error(w, "tenant not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	t, err := h.store.CreateAdminToken(ctx, ctl.AdminToken{
+		Name:     req.Name,
+		Role:     req.Role,
+		TenantID: req.TenantID,
+	})
+	if errors.Is(err, ctl.ErrInvalidAdminRole) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("create admin token failed", "err", err)
+		http.Error(w, "create admin token failed", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("admin token created", "token_id", t.ID, "role", t.Role, "tenant_id", t.TenantID, "by", adminFrom(ctx).ID)
+	writeJSON(w, http.StatusCreated, toAdminTokenResponse(*t))
+}
+
+func (h *Handler) RevokeAdminToken(w http.ResponseWriter, r *http.Request) {
+	tokenID := chi.URLParam(r, "token_id")
+
+	ctx := r.Context()
+	found, err := h.store.RevokeAdminToken(ctx, tokenID)
+	if err != nil {
+		h.log.Error("revoke admin token failed", "err", err)
+		http.Error(w, "revoke admin token failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "admin token not found", http.StatusNotFound)
+		return
+	}
+
+	t, err := h.store.GetAdminToken(ctx, tokenID)
+	if err != nil || t == nil {
+		h.log.Error("get admin token failed", "err", err)
+		http.Error(w, "get admin token failed", http.StatusInternalServerError)
+		return
+	}
+	h.log.Info("admin token revoked", "token_id", t.ID, "by", adminFrom(ctx).ID)
+	writeJSON(w, http.StatusOK, toAdminTokenResponse(*t))
+}
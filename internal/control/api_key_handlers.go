@@ -0,0 +1,228 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+)
+
+// defaultAPIKeyGrace is how long a rotated key keeps working when the
+// rotate request does not say.
+const defaultAPIKeyGrace = 24 * time.Hour
+
+// apiKeyResponse carries the secret only in the create and rotate
+// responses; it cannot be recovered afterwards.
+type apiKeyResponse struct {
+	ID                string   `json:"id"`
+	Prefix            string   `json:"prefix"`
+	Secret            string   `json:"secret,omitempty"`
+	Label             string   `json:"label"`
+	AllowedChannels   []string `json:"allowed_channels,omitempty"`
+	Scopes            []string `json:"scopes"`
+	AllowedEventTypes []string `json:"allowed_event_types,omitempty"`
+	Status            string   `json:"status"`
+	CreatedAt         string   `json:"created_at"`
+	ExpiresAt         *string  `json:"expires_at,omitempty"`
+	RevokedAt         *string  `json:"revoked_at,omitempty"`
+	LastUsedAt        *string  `json:"last_used_at,omitempty"`
+}
+
+func toAPIKeyResponse(k ctl.APIKey) apiKeyResponse {
+	format := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		s := t.Format(time.RFC3339)
+		return &s
+	}
+	return apiKeyResponse{
+		ID:                k.ID,
+		Prefix:            k.Prefix,
+		Secret:            k.Secret,
+		Label:             k.Label,
+		AllowedChannels:   k.AllowedChannels,
+		Scopes:            k.Scopes,
+		AllowedEventTypes: k.AllowedEventTypes,
+		Status:            k.Status(time.Now()),
+		CreatedAt:         k.CreatedAt.Format(time.RFC3339),
+		ExpiresAt:         format(k.ExpiresAt),
+		RevokedAt:         format(k.RevokedAt),
+		LastUsedAt:        format(k.LastUsedAt),
+	}
+}
+
+// createAPIKeyRequest without scopes gets ctl.DefaultAPIKeyScopes.
+type createAPIKeyRequest struct {
+	Label             string   `json:"label"`
+	AllowedChannels   []string `json:"allowed_channels"`
+	Scopes            []string `json:"scopes"`
+	AllowedEventTypes []string `json:"allowed_event_types"`
+	ExpiresAt         string   `json:"expires_at"`
+}
+
+type rotateAPIKeyRequest struct {
+	GracePeriod string `json:"grace_period"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	keys, err := h.store.ListAPIKeys(r.Context(), tenantID)
+	if err != nil {
+		h.log.Error("list api keys failed", "err", err)
+		http.Error(w, "list api keys failed", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]apiKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, toAPIKeyResponse(k))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	if tenantID == "" {
+		http.Error(w, "missing tenant_id", http.StatusBadRequest)
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	expiresAt, ok := apiKeyExpiry(w, req.ExpiresAt)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	key, err := h.store.CreateAPIKey(ctx, ctl.APIKey{
+		TenantID:          tenantID,
+		Label:             req.Label,
+		AllowedChannels:   req.AllowedChannels,
+		Scopes:            req.Scopes,
+		AllowedEventTypes: req.AllowedEventTypes,
+		ExpiresAt:         expiresAt,
+	})
+	if errors.Is(err, ctl.ErrInvalidScope) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("create api key failed", "err", err)
+		http.Error(w, "create api key failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toAPIKeyResponse(*key))
+}
+
+func (h *Handler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	keyID := chi.URLParam(r, "key_id")
+
+	key, err := h.store.GetAPIKey(r.Context(), tenantID, keyID)
+	if err != nil {
+		h.log.Error("get api key failed", "err", err)
+		http.Error(w, "get api key failed", http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		http.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIKeyResponse(*key))
+}
+
+// RevokeAPIKey disables the key immediately. Revoking twice keeps the
+// first revocation time.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	keyID := chi.URLParam(r, "key_id")
+
+	ctx := r.Context()
+	found, err := h.store.RevokeAPIKey(ctx, tenantID, keyID)
+	if err != nil {
+		h.log.Error("revoke api key failed", "err", err)
+		http.Error(w, "revoke api key failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+
+	key, err := h.store.GetAPIKey(ctx, tenantID, keyID)
+	if err != nil || key == nil {
+		h.log.Error("get api key failed", "err", err)
+		http.Error(w, "get api key failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIKeyResponse(*key))
+}
+
+// RotateAPIKey issues a replacement with the same label, channels and
+// scopes. The old key keeps working for grace_period (default 24h) so
+// clients can switch over; "0s" cuts it off at once.
+func (h *Handler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	keyID := chi.URLParam(r, "key_id")
+
+	var req rotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+	}
+	grace := defaultAPIKeyGrace
+	if req.GracePeriod != "" {
+		d, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid grace_period", http.StatusBadRequest)
+			return
+		}
+		grace = d
+	}
+	expiresAt, ok := apiKeyExpiry(w, req.ExpiresAt)
+	if !ok {
+		return
+	}
+
+	key, err := h.store.RotateAPIKey(r.Context(), tenantID, keyID, grace, expiresAt)
+	if errors.Is(err, ctl.ErrAPIKeyInactive) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.log.Error("rotate api key failed", "err", err)
+		http.Error(w, "rotate api key failed", http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		http.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toAPIKeyResponse(*key))
+}
+
+// apiKeyExpiry parses an optional RFC 3339 expiry, which must be in the
+// future.
+func apiKeyExpiry(w http.ResponseWriter, s string) (*time.Time, bool) {
+	if s == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil || !t.After(time.Now()) {
+		http.Error(w, "invalid expires_at, expected a future RFC 3339 time", http.StatusBadRequest)
+		return nil, false
+	}
+	return &t, true
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/config"
 	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 type App struct {
@@ -17,8 +19,14 @@ type App struct {
 	httpServer *http.Server
 }
 
-func NewApp(cfg config.Config, log logger.Logger, store *ctl.Store) *App {
-	router := NewRouter(log, store)
+func NewApp(
+	cfg config.Config,
+	log logger.Logger,
+	store *ctl.Store,
+	webhooks *webhook.Store,
+	routerEngine *routing.Engine,
+) *App {
+	router := NewRouter(log, store, webhooks, routerEngine, string(cfg.ControlAdminToken), cfg.WebhookAllowPrivateAddresses)
 
 	srv := &http.Server{
 		Addr:         cfg.ControlListenAddr,
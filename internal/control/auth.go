@@ -0,0 +1,116 @@
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+)
+
+type contextKey string
+
+const contextKeyAdmin contextKey = "admin"
+
+// bootstrapAdminID identifies the configured bootstrap token in logs.
+const bootstrapAdminID = "bootstrap"
+
+// AdminAuthMiddleware requires an "Authorization: Bearer" admin token: the
+// bootstrap token from config or one stored in admin_tokens.
+func AdminAuthMiddleware(log logger.Logger, store *ctl.Store, bootstrapToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || secret == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing admin token", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := r.Context()
+			var admin *ctl.AdminToken
+			if bootstrapToken != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(bootstrapToken)) == 1 {
+				admin = &ctl.AdminToken{ID: bootstrapAdminID, Name: bootstrapAdminID, Role: ctl.AdminRoleSuperadmin}
+			} else {
+				var err error
+				if admin, err = store.GetAdminTokenBySecret(ctx, secret); err != nil {
+					log.Error("admin auth lookup failed", "err", err)
+					http.Error(w, "auth error", http.StatusInternalServerError)
+					return
+				}
+			}
+			if admin == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "invalid admin token", http.StatusUnauthorized)
+				return
+			}
+
+			ctx = context.WithValue(ctx, contextKeyAdmin, *admin)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireSuperadmin guards endpoints that span tenants or manage admins.
+func RequireSuperadmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminFrom(r.Context()).Role != ctl.AdminRoleSuperadmin {
+			http.Error(w, "superadmin role required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readOnlyRoutes are tenant routes that change nothing although their
+// method is not GET, keyed by method and path within the tenant.
+var readOnlyRoutes = map[string]bool{
+	http.MethodPost + " /routes/dry-run": true,
+}
+
+// AuthorizeTenant guards /tenants/{tenant_id} routes: reads need access
+// to the tenant and anything else needs write access to it.
+func AuthorizeTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin := adminFrom(r.Context())
+		tenantID := chi.URLParam(r, "tenant_id")
+
+		if !admin.CanRead(tenantID) {
+			http.Error(w, "no access to tenant", http.StatusForbidden)
+			return
+		}
+		if isWrite(r) && !admin.CanWrite(tenantID) {
+			http.Error(w, "admin role "+admin.Role+" is read-only for this tenant", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireTenantWrite guards reads that expose secrets, which read-only
+// admins may not see.
+func RequireTenantWrite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin := adminFrom(r.Context())
+		if !admin.CanWrite(chi.URLParam(r, "tenant_id")) {
+			http.Error(w, "admin role "+admin.Role+" cannot read secrets for this tenant", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isWrite(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return false
+	}
+	return !readOnlyRoutes[r.Method+" "+chi.RouteContext(r.Context()).RoutePath]
+}
+
+func adminFrom(ctx context.Context) ctl.AdminToken {
+	admin, _ := ctx.Value(contextKeyAdmin).(ctl.AdminToken)
+	return admin
+}
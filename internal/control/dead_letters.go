@@ -0,0 +1,187 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
+)
+
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+type deadLetterResponse struct {
+	ID         string          `json:"id"`
+	EventID    string          `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Target     string          `json:"target"`
+	LastError  string          `json:"last_error"`
+	Attempts   int             `json:"attempts"`
+	CreatedAt  string          `json:"created_at"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	DeliveryID string          `json:"delivery_id"`
+}
+
+type redriveResponse struct {
+	DeadLetterID string `json:"dead_letter_id"`
+	DeliveryID   string `json:"delivery_id"`
+	Target       string `json:"target"`
+}
+
+func toDeadLetterResponse(dl webhook.DeadLetter, withPayload bool) deadLetterResponse {
+	resp := deadLetterResponse{
+		ID:         dl.ID,
+		EventID:    dl.EventID,
+		EventType:  dl.EventType,
+		Target:     webhook.Target(dl.EndpointID),
+		LastError:  dl.LastError,
+		Attempts:   dl.Attempts,
+		CreatedAt:  dl.CreatedAt.Format(time.RFC3339),
+		DeliveryID: dl.DeliveryID,
+	}
+	if withPayload {
+		resp.Payload = json.RawMessage(dl.Payload)
+	}
+	return resp
+}
+
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	if tenantID == "" {
+		http.Error(w, "missing tenant_id", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultDeadLetterLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDeadLetterLimit {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	ctx := r.Context()
+	dls, err := h.webhooks.ListDeadLetters(ctx, tenantID, limit)
+	if err != nil {
+		h.log.Error("list dead letters failed", "err", err)
+		http.Error(w, "list dead letters failed", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]deadLetterResponse, 0, len(dls))
+	for _, dl := range dls {
+		out = append(out, toDeadLetterResponse(dl, false))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	dl, ok := h.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, toDeadLetterResponse(*dl, true))
+}
+
+func (h *Handler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	id := chi.URLParam(r, "dead_letter_id")
+
+	ctx := r.Context()
+	deleted, err := h.webhooks.DeleteDeadLetter(ctx, tenantID, id)
+	if err != nil {
+		h.log.Error("delete dead letter failed", "err", err)
+		http.Error(w, "delete dead letter failed", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "dead letter not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RedriveDeadLetter queues a fresh delivery to the endpoint that dead-lettered
+// the event. The payload is rebuilt from the original event through the
+// endpoint's current route, so a fixed transform applies; if the event is
+// gone or no route targets the endpoint any more, the failed payload is
+// resent as is.
+func (h *Handler) RedriveDeadLetter(w http.ResponseWriter, r *http.Request) {
+	dl, ok := h.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	payload, err := h.redrivePayload(ctx, *dl)
+	if err != nil {
+		h.log.Error("redrive payload failed", "err", err, "dead_letter_id", dl.ID)
+		http.Error(w, "redrive failed", http.StatusInternalServerError)
+		return
+	}
+
+	d, err := h.webhooks.Redrive(ctx, *dl, payload)
+	if err != nil {
+		if errors.Is(err, webhook.ErrNoRedriveTarget) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.log.Error("redrive dead letter failed", "err", err)
+		http.Error(w, "redrive failed", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, redriveResponse{
+		DeadLetterID: dl.ID,
+		DeliveryID:   d.ID,
+		Target:       webhook.Target(d.EndpointID),
+	})
+}
+
+func (h *Handler) redrivePayload(ctx context.Context, dl webhook.DeadLetter) ([]byte, error) {
+	env, err := h.webhooks.OriginalEvent(ctx, dl)
+	if err != nil || env == nil {
+		return dl.Payload, err
+	}
+	// Match what the first delivery carried.
+	env.Status.DeliveryState = events.DeliveryPending
+
+	targets, err := h.router.Resolve(ctx, *env)
+	if err != nil {
+		return nil, err
+	}
+	target := webhook.Target(dl.EndpointID)
+	for _, t := range targets {
+		if t.Channel == target {
+			return json.Marshal(t.Event(*env))
+		}
+	}
+	return dl.Payload, nil
+}
+
+func (h *Handler) loadDeadLetter(w http.ResponseWriter, r *http.Request) (*webhook.DeadLetter, bool) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	id := chi.URLParam(r, "dead_letter_id")
+
+	dl, err := h.webhooks.GetDeadLetter(r.Context(), tenantID, id)
+	if err != nil {
+		h.log.Error("get dead letter failed", "err", err)
+		http.Error(w, "get dead letter failed", http.StatusInternalServerError)
+		return nil, false
+	}
+	if dl == nil {
+		http.Error(w, "dead letter not found", http.StatusNotFound)
+		return nil, false
+	}
+	return dl, true
+}
@@ -1,24 +1,37 @@
 package control
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/redact"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 type Handler struct {
-	log   logger.Logger
-	store *ctl.Store
+	log      logger.Logger
+	store    *ctl.Store
+	webhooks *webhook.Store
+	router   *routing.Engine
+
+	allowPrivateWebhooks bool
 }
 
-func NewHandler(log logger.Logger, store *ctl.Store) *Handler {
+func NewHandler(log logger.Logger, store *ctl.Store, webhooks *webhook.Store, router *routing.Engine, allowPrivateWebhooks bool) *Handler {
 	return &Handler{
-		log:   log,
-		store: store,
+		log:      log,
+		store:    store,
+		webhooks: webhooks,
+		router:   router,
+
+		allowPrivateWebhooks: allowPrivateWebhooks,
 	}
 }
 
@@ -32,31 +45,6 @@ type tenantResponse struct {
 	CreatedAt string `json:"created_at"`
 }
 
-type apiKeyResponse struct {
-	ID        string `json:"id"`
-	Secret    string `json:"secret"`
-	Label     string `json:"label"`
-	CreatedAt string `json:"created_at"`
-}
-
-type createAPIKeyRequest struct {
-	Label string `json:"label"`
-}
-
-type createRouteRequest struct {
-	MatchType     string `json:"match_type"`
-	MatchValue    string `json:"match_value"`
-	TargetChannel string `json:"target_channel"`
-}
-
-type routeResponse struct {
-	ID            string `json:"id"`
-	MatchType     string `json:"match_type"`
-	MatchValue    string `json:"match_value"`
-	TargetChannel string `json:"target_channel"`
-	CreatedAt     string `json:"created_at"`
-}
-
 func (h *Handler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 	var req createTenantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
@@ -89,8 +77,13 @@ func (h *Handler) ListTenants(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Admins scoped to one tenant only see that tenant.
+	admin := adminFrom(ctx)
 	out := make([]tenantResponse, 0, len(tenants))
 	for _, t := range tenants {
+		if !admin.CanRead(t.ID) {
+			continue
+		}
 		out = append(out, tenantResponse{
 			ID:        t.ID,
 			Name:      t.Name,
@@ -100,37 +93,114 @@ func (h *Handler) ListTenants(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, out)
 }
 
-func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+type createWebhookRequest struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+type webhookResponse struct {
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Target      string `json:"target"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func toWebhookResponse(e ctl.WebhookEndpoint) webhookResponse {
+	return webhookResponse{
+		ID:          e.ID,
+		URL:         e.URL,
+		Description: e.Description,
+		Target:      webhook.Target(e.ID),
+		CreatedAt:   e.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenant_id")
 	if tenantID == "" {
 		http.Error(w, "missing tenant_id", http.StatusBadRequest)
 		return
 	}
 
-	var req createAPIKeyRequest
+	var req createWebhookRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "url must be an absolute http(s) url", http.StatusBadRequest)
+		return
+	}
 
 	ctx := r.Context()
-	key, err := h.store.CreateAPIKey(ctx, tenantID, req.Label)
+	if !h.tenantExists(ctx, w, tenantID) {
+		return
+	}
+	if err := webhook.CheckURL(ctx, req.URL, h.allowPrivateWebhooks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e, err := h.store.CreateWebhookEndpoint(ctx, tenantID, req.URL, req.Description)
 	if err != nil {
-		h.log.Error("create api key failed", "err", err)
-		http.Error(w, "create api key failed", http.StatusInternalServerError)
+		h.log.Error("create webhook failed", "err", err)
+		http.Error(w, "create webhook failed", http.StatusInternalServerError)
 		return
 	}
+	writeJSON(w, http.StatusCreated, toWebhookResponse(*e))
+}
 
-	resp := apiKeyResponse{
-		ID:        key.ID,
-		Secret:    key.Secret,
-		Label:     key.Label,
-		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	if tenantID == "" {
+		http.Error(w, "missing tenant_id", http.StatusBadRequest)
+		return
 	}
-	writeJSON(w, http.StatusCreated, resp)
+
+	ctx := r.Context()
+	endpoints, err := h.store.ListWebhookEndpoints(ctx, tenantID)
+	if err != nil {
+		h.log.Error("list webhooks failed", "err", err)
+		http.Error(w, "list webhooks failed", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]webhookResponse, 0, len(endpoints))
+	for _, e := range endpoints {
+		out = append(out, toWebhookResponse(e))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+const defaultSigningSecretGrace = 24 * time.Hour
+
+type rotateSigningSecretRequest struct {
+	GracePeriod string `json:"grace_period"`
+}
+
+type signingSecretResponse struct {
+	ID        string  `json:"id"`
+	Secret    string  `json:"secret"`
+	CreatedAt string  `json:"created_at"`
+	ExpiresAt *string `json:"expires_at,omitempty"`
 }
 
-func (h *Handler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+func toSigningSecretResponse(ss ctl.SigningSecret) signingSecretResponse {
+	resp := signingSecretResponse{
+		ID:        ss.ID,
+		Secret:    ss.Secret,
+		CreatedAt: ss.CreatedAt.Format(time.RFC3339),
+	}
+	if ss.ExpiresAt != nil {
+		exp := ss.ExpiresAt.Format(time.RFC3339)
+		resp.ExpiresAt = &exp
+	}
+	return resp
+}
+
+func (h *Handler) ListSigningSecrets(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenant_id")
 	if tenantID == "" {
 		http.Error(w, "missing tenant_id", http.StatusBadRequest)
@@ -138,59 +208,123 @@ func (h *Handler) ListRoutes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	routes, err := h.store.ListRoutes(ctx, tenantID)
+	secrets, err := h.store.ListActiveSigningSecrets(ctx, tenantID)
 	if err != nil {
-		h.log.Error("list routes failed", "err", err)
-		http.Error(w, "list routes failed", http.StatusInternalServerError)
+		h.log.Error("list signing secrets failed", "err", err)
+		http.Error(w, "list signing secrets failed", http.StatusInternalServerError)
 		return
 	}
 
-	out := make([]routeResponse, 0, len(routes))
-	for _, rt := range routes {
-		out = append(out, routeResponse{
-			ID:            rt.ID,
-			MatchType:     rt.MatchType,
-			MatchValue:    rt.MatchValue,
-			TargetChannel: rt.TargetChannel,
-			CreatedAt:     rt.CreatedAt.Format(time.RFC3339),
-		})
+	out := make([]signingSecretResponse, 0, len(secrets))
+	for _, ss := range secrets {
+		out = append(out, toSigningSecretResponse(ss))
 	}
 	writeJSON(w, http.StatusOK, out)
 }
 
-func (h *Handler) CreateRoute(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RotateSigningSecret(w http.ResponseWriter, r *http.Request) {
 	tenantID := chi.URLParam(r, "tenant_id")
 	if tenantID == "" {
 		http.Error(w, "missing tenant_id", http.StatusBadRequest)
 		return
 	}
 
-	var req createRouteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var req rotateSigningSecretRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+	}
+	grace := defaultSigningSecretGrace
+	if req.GracePeriod != "" {
+		d, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid grace_period", http.StatusBadRequest)
+			return
+		}
+		grace = d
+	}
+
+	ctx := r.Context()
+	ss, err := h.store.RotateSigningSecret(ctx, tenantID, grace)
+	if err != nil {
+		h.log.Error("rotate signing secret failed", "err", err)
+		http.Error(w, "rotate signing secret failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toSigningSecretResponse(*ss))
+}
+
+type redactionPolicyResponse struct {
+	Policy    json.RawMessage `json:"policy"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+func (h *Handler) GetRedactionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	p, err := h.store.GetRedactionPolicy(r.Context(), tenantID)
+	if err != nil {
+		h.log.Error("get redaction policy failed", "err", err)
+		http.Error(w, "get redaction policy failed", http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "no redaction policy", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, redactionPolicyResponse{
+		Policy:    p.Policy,
+		UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+// SetRedactionPolicy replaces the tenant's policy with the request body.
+func (h *Handler) SetRedactionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	if req.MatchType == "" || req.MatchValue == "" || req.TargetChannel == "" {
-		http.Error(w, "missing fields", http.StatusBadRequest)
+	policy, err := redact.Parse(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if policy == nil {
+		http.Error(w, "missing policy", http.StatusBadRequest)
 		return
 	}
 
-	ctx := r.Context()
-	rt, err := h.store.CreateRoute(ctx, tenantID, req.MatchType, req.MatchValue, req.TargetChannel)
+	p, err := h.store.SetRedactionPolicy(r.Context(), tenantID, raw)
 	if err != nil {
-		h.log.Error("create route failed", "err", err)
-		http.Error(w, "create route failed", http.StatusInternalServerError)
+		h.log.Error("set redaction policy failed", "err", err)
+		http.Error(w, "set redaction policy failed", http.StatusInternalServerError)
 		return
 	}
+	writeJSON(w, http.StatusOK, redactionPolicyResponse{
+		Policy:    p.Policy,
+		UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (h *Handler) DeleteRedactionPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
 
-	resp := routeResponse{
-		ID:            rt.ID,
-		MatchType:     rt.MatchType,
-		MatchValue:    rt.MatchValue,
-		TargetChannel: rt.TargetChannel,
-		CreatedAt:     rt.CreatedAt.Format(time.RFC3339),
+	deleted, err := h.store.DeleteRedactionPolicy(r.Context(), tenantID)
+	if err != nil {
+		h.log.Error("delete redaction policy failed", "err", err)
+		http.Error(w, "delete redaction policy failed", http.StatusInternalServerError)
+		return
 	}
-	writeJSON(w, http.StatusCreated, resp)
+	if !deleted {
+		http.Error(w, "no redaction policy", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -198,3 +332,19 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// tenantExists replies 404 for an unknown tenant, which would otherwise
+// only surface as a foreign key error from the store.
+func (h *Handler) tenantExists(ctx context.Context, w http.ResponseWriter, tenantID string) bool {
+	t, err := h.store.GetTenant(ctx, tenantID)
+	if err != nil {
+		h.log.Error("get tenant failed", "err", err)
+		http.Error(w, "get tenant failed", http.StatusInternalServerError)
+		return false
+	}
+	if t == nil {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,523 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
+)
+
+// routeRequest is the body of both create and full update; omitted enabled
+// means true.
+type routeRequest struct {
+	MatchType      string          `json:"match_type"`
+	MatchValue     string          `json:"match_value"`
+	TargetChannel  string          `json:"target_channel"`
+	Filter         json.RawMessage `json:"filter"`
+	Transform      json.RawMessage `json:"transform"`
+	Enabled        *bool           `json:"enabled"`
+	Priority       int             `json:"priority"`
+	StopProcessing bool            `json:"stop_processing"`
+}
+
+func (req routeRequest) route(tenantID string) ctl.Route {
+	return ctl.Route{
+		TenantID:       tenantID,
+		MatchType:      req.MatchType,
+		MatchValue:     req.MatchValue,
+		TargetChannel:  req.TargetChannel,
+		Filter:         req.Filter,
+		Transform:      req.Transform,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+		Priority:       req.Priority,
+		StopProcessing: req.StopProcessing,
+	}
+}
+
+// patchRouteRequest leaves absent fields untouched; "filter": null and
+// "transform": null clear them.
+type patchRouteRequest struct {
+	MatchType      *string         `json:"match_type"`
+	MatchValue     *string         `json:"match_value"`
+	TargetChannel  *string         `json:"target_channel"`
+	Filter         json.RawMessage `json:"filter"`
+	Transform      json.RawMessage `json:"transform"`
+	Enabled        *bool           `json:"enabled"`
+	Priority       *int            `json:"priority"`
+	StopProcessing *bool           `json:"stop_processing"`
+}
+
+type routeResponse struct {
+	ID             string          `json:"id"`
+	MatchType      string          `json:"match_type"`
+	MatchValue     string          `json:"match_value"`
+	TargetChannel  string          `json:"target_channel"`
+	Filter         json.RawMessage `json:"filter,omitempty"`
+	Transform      json.RawMessage `json:"transform,omitempty"`
+	Enabled        bool            `json:"enabled"`
+	Priority       int             `json:"priority"`
+	StopProcessing bool            `json:"stop_processing"`
+	Version        int64           `json:"version"`
+	CreatedAt      string          `json:"created_at"`
+	UpdatedAt      string          `json:"updated_at"`
+}
+
+func toRouteResponse(rt ctl.Route) routeResponse {
+	return routeResponse{
+		ID:             rt.ID,
+		MatchType:      rt.MatchType,
+		MatchValue:     rt.MatchValue,
+		TargetChannel:  rt.TargetChannel,
+		Filter:         rt.Filter,
+		Transform:      rt.Transform,
+		Enabled:        rt.Enabled,
+		Priority:       rt.Priority,
+		StopProcessing: rt.StopProcessing,
+		Version:        rt.Version,
+		CreatedAt:      rt.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      rt.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func (h *Handler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	if tenantID == "" {
+		http.Error(w, "missing tenant_id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	routes, err := h.store.ListRoutes(ctx, tenantID)
+	if err != nil {
+		h.log.Error("list routes failed", "err", err)
+		http.Error(w, "list routes failed", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]routeResponse, 0, len(routes))
+	for _, rt := range routes {
+		out = append(out, toRouteResponse(rt))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) CreateRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	if tenantID == "" {
+		http.Error(w, "missing tenant_id", http.StatusBadRequest)
+		return
+	}
+
+	var req routeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.MatchType == "" || req.MatchValue == "" || req.TargetChannel == "" {
+		http.Error(w, "missing fields", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	proposed := req.route(tenantID)
+	if !h.validateRoute(ctx, w, proposed) {
+		return
+	}
+
+	rt, err := h.store.CreateRoute(ctx, proposed)
+	if errors.Is(err, ctl.ErrInvalidRoute) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("create route failed", "err", err)
+		http.Error(w, "create route failed", http.StatusInternalServerError)
+		return
+	}
+	h.router.Invalidate(tenantID)
+
+	w.Header().Set("ETag", routeETag(rt.Version))
+	writeJSON(w, http.StatusCreated, toRouteResponse(*rt))
+}
+
+func (h *Handler) GetRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	routeID := chi.URLParam(r, "route_id")
+
+	rt, err := h.store.GetRoute(r.Context(), tenantID, routeID)
+	if err != nil {
+		h.log.Error("get route failed", "err", err)
+		http.Error(w, "get route failed", http.StatusInternalServerError)
+		return
+	}
+	if rt == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("ETag", routeETag(rt.Version))
+	writeJSON(w, http.StatusOK, toRouteResponse(*rt))
+}
+
+func (h *Handler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	routeID := chi.URLParam(r, "route_id")
+
+	ifVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var req routeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.MatchType == "" || req.MatchValue == "" || req.TargetChannel == "" {
+		http.Error(w, "missing fields", http.StatusBadRequest)
+		return
+	}
+
+	rt := req.route(tenantID)
+	rt.ID = routeID
+	h.writeRoute(w, r, rt, ifVersion)
+}
+
+func (h *Handler) PatchRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	routeID := chi.URLParam(r, "route_id")
+
+	ifVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var req patchRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.store.GetRoute(r.Context(), tenantID, routeID)
+	if err != nil {
+		h.log.Error("get route failed", "err", err)
+		http.Error(w, "update route failed", http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	rt := *current
+	if req.MatchType != nil {
+		rt.MatchType = *req.MatchType
+	}
+	if req.MatchValue != nil {
+		rt.MatchValue = *req.MatchValue
+	}
+	if req.TargetChannel != nil {
+		rt.TargetChannel = *req.TargetChannel
+	}
+	if req.Filter != nil {
+		rt.Filter = req.Filter
+	}
+	if req.Transform != nil {
+		rt.Transform = req.Transform
+	}
+	if req.Enabled != nil {
+		rt.Enabled = *req.Enabled
+	}
+	if req.Priority != nil {
+		rt.Priority = *req.Priority
+	}
+	if req.StopProcessing != nil {
+		rt.StopProcessing = *req.StopProcessing
+	}
+	if rt.TargetChannel == "" {
+		http.Error(w, "missing fields", http.StatusBadRequest)
+		return
+	}
+	h.writeRoute(w, r, rt, ifVersion)
+}
+
+func (h *Handler) writeRoute(w http.ResponseWriter, r *http.Request, rt ctl.Route, ifVersion int64) {
+	ctx := r.Context()
+	if !h.validateRoute(ctx, w, rt) {
+		return
+	}
+
+	updated, err := h.store.UpdateRoute(ctx, rt, ifVersion)
+	switch {
+	case errors.Is(err, ctl.ErrInvalidRoute):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ctl.ErrRouteVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	case err != nil:
+		h.log.Error("update route failed", "err", err)
+		http.Error(w, "update route failed", http.StatusInternalServerError)
+		return
+	case updated == nil:
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+	h.router.Invalidate(rt.TenantID)
+
+	w.Header().Set("ETag", routeETag(updated.Version))
+	writeJSON(w, http.StatusOK, toRouteResponse(*updated))
+}
+
+func (h *Handler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	routeID := chi.URLParam(r, "route_id")
+
+	ifVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.store.DeleteRoute(r.Context(), tenantID, routeID, ifVersion)
+	if errors.Is(err, ctl.ErrRouteVersionConflict) {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		h.log.Error("delete route failed", "err", err)
+		http.Error(w, "delete route failed", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+	h.router.Invalidate(tenantID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type dryRunRequest struct {
+	Event struct {
+		Type     string         `json:"type"`
+		Data     map[string]any `json:"data"`
+		Metadata map[string]any `json:"metadata"`
+	} `json:"event"`
+	Route *routeRequest `json:"route"`
+}
+
+type dryRunMatch struct {
+	RouteID        string `json:"route_id,omitempty"`
+	Proposed       bool   `json:"proposed,omitempty"`
+	MatchType      string `json:"match_type"`
+	MatchValue     string `json:"match_value"`
+	TargetChannel  string `json:"target_channel"`
+	Priority       int    `json:"priority"`
+	StopProcessing bool   `json:"stop_processing"`
+}
+
+type dryRunDelivery struct {
+	Channel string               `json:"channel"`
+	Event   events.EventEnvelope `json:"event"`
+}
+
+type dryRunResponse struct {
+	Matches    []dryRunMatch    `json:"matches"`
+	Channels   []string         `json:"channels"`
+	Deliveries []dryRunDelivery `json:"deliveries"`
+	Fallback   bool             `json:"fallback"`
+}
+
+// DryRunRoutes reports how a sample event would be routed, optionally with
+// an unsaved route added to the tenant's table. Nothing is ingested.
+func (h *Handler) DryRunRoutes(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	var req dryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.Event.Type == "" {
+		http.Error(w, "missing event type", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var proposed *ctl.Route
+	if req.Route != nil {
+		if req.Route.MatchType == "" || req.Route.MatchValue == "" || req.Route.TargetChannel == "" {
+			http.Error(w, "missing route fields", http.StatusBadRequest)
+			return
+		}
+		rt := req.Route.route(tenantID)
+		if !h.validateRoute(ctx, w, rt) {
+			return
+		}
+		proposed = &rt
+	}
+
+	env := events.EventEnvelope{
+		TenantID: tenantID,
+		Type:     req.Event.Type,
+		Data:     req.Event.Data,
+		Metadata: req.Event.Metadata,
+	}
+	res, err := h.router.DryRun(ctx, env, proposed)
+	if errors.Is(err, ctl.ErrInvalidRoute) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("route dry run failed", "err", err)
+		http.Error(w, "dry run failed", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dryRunResponse{
+		Matches:    make([]dryRunMatch, 0, len(res.Matched)),
+		Channels:   res.Channels(),
+		Deliveries: make([]dryRunDelivery, 0, len(res.Targets)),
+		Fallback:   res.Fallback,
+	}
+	for _, t := range res.Targets {
+		resp.Deliveries = append(resp.Deliveries, dryRunDelivery{Channel: t.Channel, Event: t.Event(env)})
+	}
+	for _, rt := range res.Matched {
+		resp.Matches = append(resp.Matches, dryRunMatch{
+			RouteID:        rt.ID,
+			Proposed:       rt.ID == "",
+			MatchType:      rt.MatchType,
+			MatchValue:     rt.MatchValue,
+			TargetChannel:  rt.TargetChannel,
+			Priority:       rt.Priority,
+			StopProcessing: rt.StopProcessing,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type routingFallbackRequest struct {
+	Mode    string `json:"mode"`
+	Channel string `json:"channel,omitempty"`
+}
+
+func (h *Handler) GetRoutingFallback(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	fb, err := h.store.GetRoutingFallback(r.Context(), tenantID)
+	if err != nil {
+		h.log.Error("get routing fallback failed", "err", err)
+		http.Error(w, "get routing fallback failed", http.StatusInternalServerError)
+		return
+	}
+	if fb == nil {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, routingFallbackRequest{Mode: fb.Mode, Channel: fb.Channel})
+}
+
+func (h *Handler) SetRoutingFallback(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	var req routingFallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if req.Mode == ctl.FallbackChannel && !h.validateRoute(ctx, w, ctl.Route{TenantID: tenantID, TargetChannel: req.Channel}) {
+		return
+	}
+
+	fb := ctl.RoutingFallback{Mode: req.Mode, Channel: req.Channel}
+	ok, err := h.store.SetRoutingFallback(ctx, tenantID, fb)
+	if errors.Is(err, ctl.ErrInvalidFallback) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("set routing fallback failed", "err", err)
+		http.Error(w, "set routing fallback failed", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	h.router.Invalidate(tenantID)
+
+	if fb.Mode != ctl.FallbackChannel {
+		fb.Channel = ""
+	}
+	writeJSON(w, http.StatusOK, routingFallbackRequest{Mode: fb.Mode, Channel: fb.Channel})
+}
+
+// validateRoute checks the parts of a route the store cannot: filter and
+// transform syntax, and that the target is one of the tenant's channels or
+// webhooks, so a route cannot publish into another tenant's streams.
+func (h *Handler) validateRoute(ctx context.Context, w http.ResponseWriter, rt ctl.Route) bool {
+	if _, err := routing.ParseFilter(rt.Filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, err := routing.ParseTransform(rt.Transform); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	endpointID, ok := webhook.ParseTarget(rt.TargetChannel)
+	if !ok {
+		namespace := "tenant:" + rt.TenantID + ":"
+		if !strings.HasPrefix(rt.TargetChannel, namespace) || len(rt.TargetChannel) == len(namespace) {
+			http.Error(w, "target channel must be under "+namespace+" or a webhook:<endpoint_id>", http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+	e, err := h.store.GetWebhookEndpoint(ctx, rt.TenantID, endpointID)
+	if err != nil {
+		h.log.Error("get webhook failed", "err", err)
+		http.Error(w, "route validation failed", http.StatusInternalServerError)
+		return false
+	}
+	if e == nil {
+		http.Error(w, "unknown webhook target", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func routeETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// requireIfMatch reads the route version a write is conditional on. Writes
+// without If-Match are refused so concurrent edits cannot silently overwrite
+// each other.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	v := r.Header.Get("If-Match")
+	if v == "" {
+		http.Error(w, "If-Match header required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+
+	v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+	version, err := strconv.ParseInt(strings.Trim(v, `"`), 10, 64)
+	if err != nil {
+		http.Error(w, ctl.ErrRouteVersionConflict.Error(), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	return version, true
+}
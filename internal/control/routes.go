@@ -7,23 +7,69 @@ import (
 	"github.com/tejassathe/Nexus-ProtocolNetwork/internal/gateway"
 	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
-func NewRouter(log logger.Logger, store *ctl.Store) http.Handler {
+func NewRouter(log logger.Logger, store *ctl.Store, webhooks *webhook.Store, router *routing.Engine, bootstrapToken string, allowPrivateWebhooks bool) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(gateway.RequestIDMiddleware)
 	r.Use(gateway.RecoverMiddleware(log))
 	r.Use(gateway.LoggingMiddleware(log))
 
-	h := NewHandler(log, store)
+	h := NewHandler(log, store, webhooks, router, allowPrivateWebhooks)
 
 	r.Route("/control", func(cr chi.Router) {
-		cr.Post("/tenants", h.CreateTenant)
+		cr.Use(AdminAuthMiddleware(log, store, bootstrapToken))
+
+		cr.With(RequireSuperadmin).Post("/tenants", h.CreateTenant)
 		cr.Get("/tenants", h.ListTenants)
-		cr.Post("/tenants/{tenant_id}/api-keys", h.CreateAPIKey)
-		cr.Get("/tenants/{tenant_id}/routes", h.ListRoutes)
-		cr.Post("/tenants/{tenant_id}/routes", h.CreateRoute)
+
+		cr.Route("/admin-tokens", func(ar chi.Router) {
+			ar.Use(RequireSuperadmin)
+			ar.Get("/", h.ListAdminTokens)
+			ar.Post("/", h.CreateAdminToken)
+			ar.Post("/{token_id}/revoke", h.RevokeAdminToken)
+		})
+
+		cr.Route("/tenants/{tenant_id}", func(tr chi.Router) {
+			tr.Use(AuthorizeTenant)
+			tr.Get("/api-keys", h.ListAPIKeys)
+			tr.Post("/api-keys", h.CreateAPIKey)
+			tr.Get("/api-keys/{key_id}", h.GetAPIKey)
+			tr.Post("/api-keys/{key_id}/revoke", h.RevokeAPIKey)
+			tr.Post("/api-keys/{key_id}/rotate", h.RotateAPIKey)
+			tr.Get("/routes", h.ListRoutes)
+			tr.Post("/routes", h.CreateRoute)
+			tr.Post("/routes/dry-run", h.DryRunRoutes)
+			tr.Get("/routes/{route_id}", h.GetRoute)
+			tr.Put("/routes/{route_id}", h.UpdateRoute)
+			tr.Patch("/routes/{route_id}", h.PatchRoute)
+			tr.Delete("/routes/{route_id}", h.DeleteRoute)
+			tr.Get("/routing-fallback", h.GetRoutingFallback)
+			tr.Put("/routing-fallback", h.SetRoutingFallback)
+			tr.Get("/webhooks", h.ListWebhooks)
+			tr.Post("/webhooks", h.CreateWebhook)
+			// Signing secrets are returned in plaintext, so listing them
+			// needs write access like rotating them does.
+			tr.With(RequireTenantWrite).Get("/signing-secrets", h.ListSigningSecrets)
+			tr.Post("/signing-secrets", h.RotateSigningSecret)
+			tr.Get("/redaction-policy", h.GetRedactionPolicy)
+			tr.Put("/redaction-policy", h.SetRedactionPolicy)
+			tr.Delete("/redaction-policy", h.DeleteRedactionPolicy)
+			tr.Get("/schemas", h.ListSchemas)
+			tr.Post("/schemas", h.RegisterSchema)
+			tr.Get("/schemas/{event_type}/versions/{version}", h.GetSchema)
+			tr.Post("/schemas/{event_type}/versions/{version}/activate", h.ActivateSchema)
+			tr.Delete("/schemas/{event_type}/active", h.DeactivateSchema)
+			tr.Get("/schema-mode", h.GetSchemaMode)
+			tr.Put("/schema-mode", h.SetSchemaMode)
+			tr.Get("/dead-letters", h.ListDeadLetters)
+			tr.Get("/dead-letters/{dead_letter_id}", h.GetDeadLetter)
+			tr.Delete("/dead-letters/{dead_letter_id}", h.DeleteDeadLetter)
+			tr.Post("/dead-letters/{dead_letter_id}/redrive", h.RedriveDeadLetter)
+		})
 	})
 
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,216 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
+)
+
+type registerSchemaRequest struct {
+	EventType string          `json:"event_type"`
+	Schema    json.RawMessage `json:"schema"`
+}
+
+type schemaResponse struct {
+	EventType string          `json:"event_type"`
+	Version   int             `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	Active    bool            `json:"active"`
+	CreatedAt string          `json:"created_at"`
+}
+
+func toSchemaResponse(es ctl.EventSchema) schemaResponse {
+	return schemaResponse{
+		EventType: es.EventType,
+		Version:   es.Version,
+		Schema:    es.Schema,
+		Active:    es.Active,
+		CreatedAt: es.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type schemaModeRequest struct {
+	Mode string `json:"mode"`
+}
+
+// ListSchemas lists every registered version; ?event_type= narrows it to
+// one event type.
+func (h *Handler) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	schemas, err := h.store.ListSchemas(r.Context(), tenantID, r.URL.Query().Get("event_type"))
+	if err != nil {
+		h.log.Error("list schemas failed", "err", err)
+		http.Error(w, "list schemas failed", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]schemaResponse, 0, len(schemas))
+	for _, es := range schemas {
+		out = append(out, toSchemaResponse(es))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// RegisterSchema adds a new version for the event type and activates it.
+func (h *Handler) RegisterSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	var req registerSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.EventType == "" || len(req.Schema) == 0 {
+		http.Error(w, "missing fields", http.StatusBadRequest)
+		return
+	}
+	if _, err := schema.Compile(req.Schema); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if !h.tenantExists(ctx, w, tenantID) {
+		return
+	}
+	es, err := h.store.RegisterSchema(ctx, tenantID, req.EventType, req.Schema)
+	if err != nil {
+		h.log.Error("register schema failed", "err", err)
+		http.Error(w, "register schema failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toSchemaResponse(*es))
+}
+
+func (h *Handler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	eventType := chi.URLParam(r, "event_type")
+	version, ok := schemaVersionParam(w, r)
+	if !ok {
+		return
+	}
+
+	es, err := h.store.GetSchema(r.Context(), tenantID, eventType, version)
+	if err != nil {
+		h.log.Error("get schema failed", "err", err)
+		http.Error(w, "get schema failed", http.StatusInternalServerError)
+		return
+	}
+	if es == nil {
+		http.Error(w, "schema not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSchemaResponse(*es))
+}
+
+// ActivateSchema switches the event type to an existing version, e.g. to
+// roll back a bad registration.
+func (h *Handler) ActivateSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	eventType := chi.URLParam(r, "event_type")
+	version, ok := schemaVersionParam(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if !h.tenantExists(ctx, w, tenantID) {
+		return
+	}
+	found, err := h.store.ActivateSchema(ctx, tenantID, eventType, version)
+	if err != nil {
+		h.log.Error("activate schema failed", "err", err)
+		http.Error(w, "activate schema failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "schema not found", http.StatusNotFound)
+		return
+	}
+
+	es, err := h.store.GetSchema(ctx, tenantID, eventType, version)
+	if err != nil || es == nil {
+		h.log.Error("get schema failed", "err", err)
+		http.Error(w, "get schema failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSchemaResponse(*es))
+}
+
+// DeactivateSchema stops validating the event type; its versions are kept.
+func (h *Handler) DeactivateSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+	eventType := chi.URLParam(r, "event_type")
+
+	deactivated, err := h.store.DeactivateSchema(r.Context(), tenantID, eventType)
+	if err != nil {
+		h.log.Error("deactivate schema failed", "err", err)
+		http.Error(w, "deactivate schema failed", http.StatusInternalServerError)
+		return
+	}
+	if !deactivated {
+		http.Error(w, "no active schema", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetSchemaMode(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	mode, err := h.store.GetSchemaMode(r.Context(), tenantID)
+	if err != nil {
+		h.log.Error("get schema mode failed", "err", err)
+		http.Error(w, "get schema mode failed", http.StatusInternalServerError)
+		return
+	}
+	if mode == "" {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, schemaModeRequest{Mode: mode})
+}
+
+// SetSchemaMode chooses whether events failing their schema are rejected
+// or accepted and tagged invalid.
+func (h *Handler) SetSchemaMode(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	var req schemaModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.store.SetSchemaMode(r.Context(), tenantID, req.Mode)
+	if errors.Is(err, ctl.ErrInvalidSchemaMode) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.log.Error("set schema mode failed", "err", err)
+		http.Error(w, "set schema mode failed", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func schemaVersionParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || version < 1 {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return 0, false
+	}
+	return version, true
+}
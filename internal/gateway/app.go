@@ -10,7 +10,10 @@ import (
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/redact"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 type App struct {
@@ -26,12 +29,18 @@ func NewApp(
 	eventSvc events.Service,
 	ctrlStore *control.Store,
 	routerEngine *routing.Engine,
+	sequencer realtime.Sequencer,
+	history realtime.History,
+	webhooks *webhook.Store,
+	redactor *redact.Redactor,
+	schemas *schema.Registry,
+	keyUsage *control.KeyUsage,
 ) *App {
 	wsHub := realtime.NewWSHub()
 	sseBroker := realtime.NewSSEBroker()
-	rtBroadcaster := realtime.NewBroadcaster(log, wsHub, sseBroker)
+	rtBroadcaster := realtime.NewBroadcaster(log, wsHub, sseBroker, sequencer, history)
 
-	router := NewRouter(log, eventSvc, ctrlStore, routerEngine, wsHub, sseBroker, rtBroadcaster)
+	router := NewRouter(cfg, log, eventSvc, ctrlStore, routerEngine, wsHub, sseBroker, rtBroadcaster, history, webhooks, redactor, schemas, keyUsage)
 
 	srv := &http.Server{
 		Addr:         cfg.ListenAddr,
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
+)
+
+var errBatchTooLarge = errors.New("batch exceeds maximum size")
+
+type batchItemResult struct {
+	Index    int    `json:"index"`
+	EventID  string `json:"event_id,omitempty"`
+	Status   string `json:"status"`
+	Replayed bool   `json:"replayed,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Errors lists the field errors of an item rejected by its schema.
+	Errors []schema.FieldError `json:"errors,omitempty"`
+}
+
+type batchIngestResponse struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Results  []batchItemResult `json:"results"`
+}
+
+// HandleRESTBatchIngest accepts either a JSON array of events, an NDJSON
+// body (one event per line) or a CloudEvents JSON batch
+// (application/cloudevents-batch+json). Items are validated and ingested independently;
+// a bad item is reported in its result without failing the rest.
+func (h *EventHandler) HandleRESTBatchIngest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, _ := ctx.Value(ContextKeyTenantID).(string)
+
+	body := http.MaxBytesReader(w, r.Body, h.maxBatchBytes)
+
+	var (
+		items []json.RawMessage
+		err   error
+	)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		items, err = readNDJSONItems(body, h.maxBatchSize)
+	default:
+		items, err = readJSONArrayItems(body, h.maxBatchSize)
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", h.maxBatchBytes), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, errBatchTooLarge):
+			http.Error(w, fmt.Sprintf("batch exceeds %d events", h.maxBatchSize), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, "invalid batch body: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "empty batch", http.StatusBadRequest)
+		return
+	}
+
+	src := restSource(r)
+	resp := batchIngestResponse{Results: make([]batchItemResult, 0, len(items))}
+
+	for i, raw := range items {
+		res := batchItemResult{Index: i}
+
+		if item, err := decodeBatchItem(raw, mediaType); err != nil {
+			res.Status = "rejected"
+			res.Error = err.Error()
+		} else if env, err := h.ingest(ctx, tenantID, item, src); err != nil {
+			var invalid *schemaError
+			switch {
+			case errors.Is(err, events.ErrDuplicate):
+				res.Status = "accepted"
+				res.EventID = env.ID
+				res.Replayed = true
+			case errors.Is(err, events.ErrMissingType),
+				errors.Is(err, events.ErrInvalidType),
+				errors.Is(err, events.ErrInvalidEventID),
+				errors.Is(err, errEventTypeForbidden):
+				res.Status = "rejected"
+				res.Error = err.Error()
+			case errors.As(err, &invalid):
+				res.Status = "rejected"
+				res.Error = invalid.Error()
+				res.Errors = invalid.errs
+			default:
+				h.log.Error("failed to ingest batch item", "err", err, "index", i)
+				res.Status = "rejected"
+				res.Error = "failed to ingest event"
+			}
+		} else {
+			res.Status = "accepted"
+			res.EventID = env.ID
+		}
+
+		if res.Status == "accepted" {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	status := http.StatusAccepted
+	if resp.Rejected > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeJSON(w, status, resp)
+}
+
+func decodeBatchItem(raw json.RawMessage, mediaType string) (events.IngestRequest, error) {
+	if mediaType == events.CloudEventsBatchContentType {
+		return events.ParseStructuredCloudEvent(raw)
+	}
+	var item restIngestRequest
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return events.IngestRequest{}, errors.New("invalid json")
+	}
+	return item.ingestRequest(), nil
+}
+
+func readJSONArrayItems(r io.Reader, maxItems int) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("expected a json array")
+	}
+
+	var items []json.RawMessage
+	for dec.More() {
+		if len(items) == maxItems {
+			return nil, errBatchTooLarge
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		items = append(items, raw)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func readNDJSONItems(r io.Reader, maxItems int) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if len(items) == maxItems {
+				return nil, errBatchTooLarge
+			}
+			items = append(items, json.RawMessage(trimmed))
+		}
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -3,13 +3,23 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/config"
+	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/redact"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 type EventHandler struct {
@@ -17,31 +27,81 @@ type EventHandler struct {
 	eventSvc      events.Service
 	rtBroadcaster realtime.Broadcaster
 	router        *routing.Engine
+	webhooks      *webhook.Store
+	redactor      *redact.Redactor
+	schemas       *schema.Registry
+
+	maxEventBytes int64
+	maxBatchSize  int
+	maxBatchBytes int64
 }
 
 func NewEventHandler(
+	cfg config.Config,
 	log logger.Logger,
 	es events.Service,
 	rt realtime.Broadcaster,
 	router *routing.Engine,
+	webhooks *webhook.Store,
+	redactor *redact.Redactor,
+	schemas *schema.Registry,
 ) *EventHandler {
 	return &EventHandler{
 		log:           log,
 		eventSvc:      es,
 		rtBroadcaster: rt,
 		router:        router,
+		webhooks:      webhooks,
+		redactor:      redactor,
+		schemas:       schemas,
+		maxEventBytes: cfg.MaxEventBytes,
+		maxBatchSize:  cfg.MaxBatchSize,
+		maxBatchBytes: cfg.MaxBatchBytes,
 	}
 }
 
 type restIngestRequest struct {
-	Type     string         `json:"type"`
-	Data     map[string]any `json:"data"`
-	Metadata map[string]any `json:"metadata"`
+	ID             string         `json:"id"`
+	Type           string         `json:"type"`
+	Data           map[string]any `json:"data"`
+	Metadata       map[string]any `json:"metadata"`
+	IdempotencyKey string         `json:"idempotency_key"`
+}
+
+func (req restIngestRequest) ingestRequest() events.IngestRequest {
+	return events.IngestRequest{
+		Type:           req.Type,
+		Data:           req.Data,
+		Metadata:       req.Metadata,
+		EventID:        req.ID,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+}
+
+// schemaError rejects an event whose data fails its type's active schema.
+type schemaError struct {
+	version int
+	errs    []schema.FieldError
+}
+
+func (e *schemaError) Error() string {
+	return fmt.Sprintf("event data does not match schema version %d", e.version)
+}
+
+// errEventTypeForbidden rejects an event type outside the key's allowed
+// event types.
+var errEventTypeForbidden = errors.New("api key may not publish this event type")
+
+type schemaErrorResponse struct {
+	Error         string              `json:"error"`
+	SchemaVersion int                 `json:"schema_version"`
+	Errors        []schema.FieldError `json:"errors"`
 }
 
 type restIngestResponse struct {
-	EventID string `json:"event_id"`
-	Status  string `json:"status"`
+	EventID  string `json:"event_id"`
+	Status   string `json:"status"`
+	Replayed bool   `json:"replayed,omitempty"`
 }
 
 func (h *EventHandler) HandleRESTIngest(w http.ResponseWriter, r *http.Request) {
@@ -49,59 +109,227 @@ func (h *EventHandler) HandleRESTIngest(w http.ResponseWriter, r *http.Request)
 
 	tenantID, _ := ctx.Value(ContextKeyTenantID).(string)
 
-	var reqBody restIngestRequest
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "invalid json body", http.StatusBadRequest)
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxEventBytes)
+	req, err := readIngestRequest(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", h.maxEventBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = key
+	}
+
+	env, err := h.ingest(ctx, tenantID, req, restSource(r))
+	if err != nil {
+		var invalid *schemaError
+		switch {
+		case errors.Is(err, events.ErrDuplicate):
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeJSON(w, http.StatusOK, restIngestResponse{
+				EventID:  env.ID,
+				Status:   "accepted",
+				Replayed: true,
+			})
+		case errors.Is(err, events.ErrMissingType), errors.Is(err, events.ErrInvalidType),
+			errors.Is(err, events.ErrInvalidEventID):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, errEventTypeForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.As(err, &invalid):
+			writeJSON(w, http.StatusUnprocessableEntity, schemaErrorResponse{
+				Error:         invalid.Error(),
+				SchemaVersion: invalid.version,
+				Errors:        invalid.errs,
+			})
+		default:
+			h.log.Error("failed to ingest event", "err", err)
+			http.Error(w, "failed to ingest event", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	src := events.SourceInfo{
+	resp := restIngestResponse{
+		EventID: env.ID,
+		Status:  "accepted",
+	}
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// readIngestRequest decodes a native event body, a structured-mode
+// CloudEvent (application/cloudevents+json) or, when ce-* headers are
+// present, a binary-mode CloudEvent whose body is the data.
+func readIngestRequest(r *http.Request) (events.IngestRequest, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == events.CloudEventsContentType:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return events.IngestRequest{}, bodyError(err, "invalid body")
+		}
+		return events.ParseStructuredCloudEvent(body)
+
+	case r.Header.Get("Ce-Specversion") != "":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return events.IngestRequest{}, bodyError(err, "invalid body")
+		}
+		attrs := make(map[string]string)
+		for name, values := range r.Header {
+			attr, ok := strings.CutPrefix(strings.ToLower(name), "ce-")
+			if !ok || len(values) == 0 {
+				continue
+			}
+			// Header values are percent-encoded per the HTTP binding.
+			if attrs[attr], err = url.PathUnescape(values[0]); err != nil {
+				return events.IngestRequest{}, fmt.Errorf("%w: header %s is not percent-encoded", events.ErrInvalidCloudEvent, name)
+			}
+		}
+		return events.ParseBinaryCloudEvent(attrs, r.Header.Get("Content-Type"), body)
+	}
+
+	var body restIngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return events.IngestRequest{}, bodyError(err, "invalid json body")
+	}
+	return body.ingestRequest(), nil
+}
+
+// bodyError keeps an oversized body distinguishable and replaces any other
+// read error with msg.
+func bodyError(err error, msg string) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return errors.New(msg)
+}
+
+func restSource(r *http.Request) events.SourceInfo {
+	return events.SourceInfo{
 		Protocol:  "REST",
 		Endpoint:  r.URL.Path,
 		IP:        r.RemoteAddr,
 		UserAgent: r.UserAgent(),
 	}
+}
 
-	env, err := h.eventSvc.Ingest(ctx, tenantID, events.IngestRequest{
-		Type:     reqBody.Type,
-		Data:     reqBody.Data,
-		Metadata: reqBody.Metadata,
-		Source:   src,
-	})
-	if err != nil {
-		if err == events.ErrMissingType {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+// ingest persists one event and fans it out to every route target. A
+// duplicate returns the original envelope with events.ErrDuplicate and is not
+// fanned out again. Data failing its schema yields a *schemaError unless the
+// tenant tags invalid events instead; a type the key may not publish yields
+// errEventTypeForbidden.
+func (h *EventHandler) ingest(
+	ctx context.Context,
+	tenantID string,
+	req events.IngestRequest,
+	src events.SourceInfo,
+) (events.EventEnvelope, error) {
+	allowed, _ := ctx.Value(ContextKeyEventTypes).([]string)
+	if req.Type != "" && !ctl.MatchPatterns(allowed, req.Type) {
+		return events.EventEnvelope{}, errEventTypeForbidden
+	}
+
+	// CloudEvents attributes arrive in req.Source.Extra.
+	src.Extra = req.Source.Extra
+	req.Source = src
+
+	// Validate the data as the producer sent it; redaction may replace
+	// values with ones the schema does not allow.
+	if h.schemas != nil {
+		res, err := h.schemas.Check(ctx, tenantID, req.Type, req.Data)
+		if err != nil {
+			return events.EventEnvelope{}, err
+		}
+		if res != nil {
+			if res.Reject {
+				return events.EventEnvelope{}, &schemaError{version: res.Version, errs: res.Errors}
+			}
+			req.Validation = &events.Validation{SchemaVersion: res.Version, Valid: res.Valid(), Errors: res.Errors}
 		}
-		h.log.Error("failed to ingest event", "err", err)
-		http.Error(w, "failed to ingest event", http.StatusInternalServerError)
-		return
 	}
 
-	channels, err := h.resolveChannels(ctx, tenantID, env.Type)
+	// Redact before anything is stored or fanned out.
+	if h.redactor != nil {
+		var err error
+		if req.Data, req.Metadata, err = h.redactor.Redact(ctx, tenantID, req.Data, req.Metadata); err != nil {
+			return events.EventEnvelope{}, err
+		}
+	}
+
+	env, err := h.eventSvc.Ingest(ctx, tenantID, req)
 	if err != nil {
-		h.log.Warn("resolve channels failed; using default", "err", err)
+		return env, err
 	}
 
-	if len(channels) == 0 {
-		channels = []string{DefaultTenantChannel(tenantID)}
+	targets, err := h.resolveTargets(ctx, env)
+	if err != nil {
+		h.log.Warn("resolve targets failed; using default", "err", err)
+		targets = []routing.Target{{Channel: DefaultTenantChannel(tenantID)}}
+	}
+	if len(targets) == 0 {
+		h.log.Debug("event dropped by routing fallback", "event_id", env.ID, "tenant_id", tenantID)
 	}
 
-	for _, ch := range channels {
-		if err := h.rtBroadcaster.BroadcastEvent(ctx, ch, env); err != nil {
+	var enqueued int
+	var failed bool
+	for _, t := range targets {
+		out := t.Event(env)
+		if endpointID, ok := webhook.ParseTarget(t.Channel); ok {
+			if h.enqueueWebhook(ctx, out, endpointID) {
+				enqueued++
+			} else {
+				failed = true
+			}
+			continue
+		}
+		if err := h.rtBroadcaster.BroadcastEvent(ctx, t.Channel, out); err != nil {
 			h.log.Warn("failed to broadcast event",
 				"err", err,
-				"channel", ch,
+				"channel", t.Channel,
 				"event_id", env.ID,
 			)
+			failed = true
 		}
 	}
 
-	resp := restIngestResponse{
-		EventID: env.ID,
-		Status:  "accepted",
+	// Webhook deliveries settle the event's state as they finish; without
+	// any, fan-out is all there is.
+	if enqueued == 0 {
+		state := events.DeliveryDelivered
+		switch {
+		case failed:
+			state = events.DeliveryFailed
+		case len(targets) == 0:
+			state = events.DeliveryDropped
+		}
+		if err := h.eventSvc.SetDeliveryState(ctx, env.ID, state); err != nil {
+			h.log.Warn("failed to set event delivery state", "err", err, "event_id", env.ID)
+		}
 	}
-	writeJSON(w, http.StatusAccepted, resp)
+
+	return env, nil
+}
+
+func (h *EventHandler) enqueueWebhook(ctx context.Context, env events.EventEnvelope, endpointID string) bool {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		h.log.Error("failed to marshal event for webhook", "err", err, "event_id", env.ID)
+		return false
+	}
+	if _, err := h.webhooks.Enqueue(ctx, env.TenantID, env.ID, endpointID, payload); err != nil {
+		h.log.Warn("failed to enqueue webhook delivery",
+			"err", err,
+			"endpoint_id", endpointID,
+			"event_id", env.ID,
+		)
+		return false
+	}
+	return true
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -111,12 +339,12 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 }
 
 func DefaultTenantChannel(tenantID string) string {
-	return fmt.Sprintf("tenant:%s:events", tenantID)
+	return routing.DefaultChannel(tenantID)
 }
 
-func (h *EventHandler) resolveChannels(ctx context.Context, tenantID, eventType string) ([]string, error) {
+func (h *EventHandler) resolveTargets(ctx context.Context, env events.EventEnvelope) ([]routing.Target, error) {
 	if h.router == nil {
-		return nil, nil
+		return []routing.Target{{Channel: DefaultTenantChannel(env.TenantID)}}, nil
 	}
-	return h.router.ResolveChannels(ctx, tenantID, eventType)
+	return h.router.Resolve(ctx, env)
 }
@@ -9,8 +9,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/clienttoken"
 	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
 )
 
 type contextKey string
@@ -19,6 +22,12 @@ const (
 	ContextKeyRequestID contextKey = "request_id"
 	ContextKeyTenantID  contextKey = "tenant_id"
 	ContextKeyAPIKey    contextKey = "api_key"
+	ContextKeyPrincipal contextKey = "principal"
+	// ContextKeyScopes holds the []string scopes of the request's key.
+	ContextKeyScopes contextKey = "scopes"
+	// ContextKeyEventTypes holds the []string event type patterns the key
+	// may publish; nil means any.
+	ContextKeyEventTypes contextKey = "event_types"
 )
 
 func RequestIDMiddleware(next http.Handler) http.Handler {
@@ -98,38 +107,109 @@ func (s *statusRecorder) Push(target string, opts *http.PushOptions) error {
 	return http.ErrNotSupported
 }
 
-func AuthMiddleware(log logger.Logger, store *ctl.Store) func(http.Handler) http.Handler {
+// AuthMiddleware resolves the tenant from X-Api-Key, or from a client
+// token for browsers that cannot set headers, and records the key's use
+// with usage.
+func AuthMiddleware(log logger.Logger, store *ctl.Store, usage *ctl.KeyUsage, tokenSecret []byte) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			headerTenant := r.Header.Get("X-Tenant-Id")
 			apiKey := r.Header.Get("X-Api-Key")
+			token := clientToken(r)
 
-			if apiKey == "" {
+			if apiKey == "" && token == "" {
 				http.Error(w, "missing X-Api-Key", http.StatusUnauthorized)
 				return
 			}
 
 			ctx := r.Context()
-			tenant, _, err := store.GetTenantByAPIKey(ctx, apiKey)
-			if err != nil {
-				log.Error("auth lookup failed", "err", err)
-				http.Error(w, "auth error", http.StatusInternalServerError)
-				return
-			}
-			if tenant == nil {
-				http.Error(w, "invalid api key", http.StatusUnauthorized)
-				return
+			var (
+				key      *ctl.APIKey
+				channels []string
+				scopes   []string
+			)
+			if apiKey != "" {
+				tenant, k, err := store.GetTenantByAPIKey(ctx, apiKey)
+				if err != nil {
+					log.Error("auth lookup failed", "err", err)
+					http.Error(w, "auth error", http.StatusInternalServerError)
+					return
+				}
+				if tenant == nil {
+					http.Error(w, "invalid api key", http.StatusUnauthorized)
+					return
+				}
+				key, channels, scopes = k, k.AllowedChannels, k.Scopes
+			} else {
+				claims, err := clienttoken.Verify(tokenSecret, token, time.Now())
+				if err != nil {
+					http.Error(w, "invalid client token", http.StatusUnauthorized)
+					return
+				}
+				// The minting key must still be active, so revoking it
+				// cuts off its tokens too.
+				k, err := store.GetAPIKey(ctx, claims.TenantID, claims.KeyID)
+				if err != nil {
+					log.Error("auth lookup failed", "err", err)
+					http.Error(w, "auth error", http.StatusInternalServerError)
+					return
+				}
+				if k == nil || k.Status(time.Now()) != ctl.APIKeyActive {
+					http.Error(w, "invalid client token", http.StatusUnauthorized)
+					return
+				}
+				key, channels, scopes = k, claims.Channels, []string{ctl.ScopeChannelsSubscribe}
 			}
+			usage.Touch(key.ID)
 
-			if headerTenant != "" && headerTenant != tenant.ID {
+			if headerTenant != "" && headerTenant != key.TenantID {
 				http.Error(w, "tenant mismatch", http.StatusForbidden)
 				return
 			}
 
-			ctx = context.WithValue(ctx, ContextKeyTenantID, tenant.ID)
-			ctx = context.WithValue(ctx, ContextKeyAPIKey, apiKey)
+			ctx = context.WithValue(ctx, ContextKeyTenantID, key.TenantID)
+			if apiKey != "" {
+				ctx = context.WithValue(ctx, ContextKeyAPIKey, apiKey)
+				ctx = context.WithValue(ctx, ContextKeyEventTypes, key.AllowedEventTypes)
+			}
+			ctx = context.WithValue(ctx, ContextKeyScopes, scopes)
+			ctx = context.WithValue(ctx, ContextKeyPrincipal, realtime.Principal{
+				TenantID:        key.TenantID,
+				KeyID:           key.ID,
+				AllowedChannels: channels,
+			})
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// clientToken returns the client token from the access_token query
+// parameter or, for WebSocket upgrades, from a Sec-WebSocket-Protocol
+// entry offered next to clientTokenSubprotocol.
+func clientToken(r *http.Request) string {
+	if t := r.URL.Query().Get("access_token"); t != "" {
+		return t
+	}
+	for _, p := range websocket.Subprotocols(r) {
+		if clienttoken.Looks(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// RequireScope rejects requests whose key lacks scope. It must run after
+// AuthMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, _ := r.Context().Value(ContextKeyScopes).([]string)
+			if !ctl.HasScope(scopes, scope) {
+				http.Error(w, "api key lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
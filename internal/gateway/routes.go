@@ -4,14 +4,19 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/config"
 	ctl "github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/redact"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/webhook"
 )
 
 func NewRouter(
+	cfg config.Config,
 	log logger.Logger,
 	eventSvc events.Service,
 	ctrlStore *ctl.Store,
@@ -19,13 +24,18 @@ func NewRouter(
 	wsHub *realtime.WSHub,
 	sseBroker *realtime.SSEBroker,
 	rtBroadcaster realtime.Broadcaster,
+	history realtime.History,
+	webhooks *webhook.Store,
+	redactor *redact.Redactor,
+	schemas *schema.Registry,
+	keyUsage *ctl.KeyUsage,
 ) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(RequestIDMiddleware)
 	r.Use(RecoverMiddleware(log))
 	r.Use(LoggingMiddleware(log))
-	r.Use(AuthMiddleware(log, ctrlStore))
+	r.Use(AuthMiddleware(log, ctrlStore, keyUsage, []byte(cfg.ClientTokenSecret)))
 
 	r.Group(func(r chi.Router) {
 		r.Use()
@@ -36,13 +46,26 @@ func NewRouter(
 	})
 
 	r.Route("/api/v1", func(api chi.Router) {
-		h := NewEventHandler(log, eventSvc, rtBroadcaster, routerEngine)
-		api.Post("/events", h.HandleRESTIngest)
+		api.Group(func(api chi.Router) {
+			api.Use(RequireScope(ctl.ScopeEventsPublish))
+			h := NewEventHandler(cfg, log, eventSvc, rtBroadcaster, routerEngine, webhooks, redactor, schemas)
+			api.Post("/events", h.HandleRESTIngest)
+			api.Post("/events/batch", h.HandleRESTBatchIngest)
+		})
+
+		api.With(RequireScope(ctl.ScopeChannelsSubscribe)).
+			Post("/client-tokens", NewClientTokenHandler(cfg, log))
 	})
 
-	r.Get("/ws", NewWSHandler(log, wsHub))
+	channelAuth := realtime.NewChannelAuthorizer()
+
+	r.Group(func(r chi.Router) {
+		r.Use(RequireScope(ctl.ScopeChannelsSubscribe))
+
+		r.Get("/ws", NewWSHandler(log, wsHub, history, channelAuth))
 
-	r.Get("/sse/stream", NewSSEHandler(log, sseBroker))
+		r.Get("/sse/stream", NewSSEHandler(log, sseBroker, history, channelAuth))
+	})
 
 	return r
 }
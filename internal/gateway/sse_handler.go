@@ -1,15 +1,25 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
 )
 
-func NewSSEHandler(log logger.Logger, broker *realtime.SSEBroker) http.HandlerFunc {
+const sseReplayLimit = 1000
+
+func NewSSEHandler(
+	log logger.Logger,
+	broker *realtime.SSEBroker,
+	history realtime.History,
+	auth realtime.ChannelAuthorizer,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		tenantID, _ := ctx.Value(ContextKeyTenantID).(string)
@@ -19,6 +29,29 @@ func NewSSEHandler(log logger.Logger, broker *realtime.SSEBroker) http.HandlerFu
 			channel = DefaultTenantChannel(tenantID)
 		}
 
+		principal, _ := ctx.Value(ContextKeyPrincipal).(realtime.Principal)
+		if err := auth.AuthorizeSubscribe(principal, channel); err != nil {
+			log.Warn("sse subscribe denied", "tenant_id", tenantID, "channel", channel)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		format, err := realtime.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var lastSeq int64
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			seq, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || seq < 0 {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			lastSeq = seq
+		}
+
 		flusher, ok := w.(http.Flusher)
 		if !ok {
 			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
@@ -29,12 +62,21 @@ func NewSSEHandler(log logger.Logger, broker *realtime.SSEBroker) http.HandlerFu
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
 
+		// Subscribe before reading history so nothing published during the
+		// replay is missed; duplicates are skipped by sequence below.
 		client := broker.Subscribe(channel)
 		defer broker.Unsubscribe(channel, client)
 
-		log.Info("sse client subscribed", "tenant_id", tenantID, "channel", channel)
+		log.Info("sse client subscribed", "tenant_id", tenantID, "channel", channel, "last_event_id", lastSeq)
 
 		fmt.Fprintf(w, ": connected\n\n")
+
+		if lastSeq > 0 {
+			if lastSeq, err = replaySSE(ctx, w, history, channel, lastSeq, format, log); err != nil {
+				log.Error("sse replay failed", "err", err, "tenant_id", tenantID, "channel", channel)
+				return
+			}
+		}
 		flusher.Flush()
 
 		for {
@@ -44,8 +86,22 @@ func NewSSEHandler(log logger.Logger, broker *realtime.SSEBroker) http.HandlerFu
 					log.Info("sse client channel closed", "tenant_id", tenantID, "channel", channel)
 					return
 				}
+				if msg.Seq <= lastSeq {
+					continue
+				}
 
-				fmt.Fprintf(w, "data: %s\n\n", msg)
+				if lastSeq > 0 && msg.Seq > lastSeq+1 {
+					// The broker dropped messages while this client lagged;
+					// fill the gap from history instead.
+					if lastSeq, err = replaySSE(ctx, w, history, channel, lastSeq, format, log); err != nil {
+						log.Error("sse catch-up failed", "err", err, "tenant_id", tenantID, "channel", channel)
+						return
+					}
+				}
+				if msg.Seq > lastSeq {
+					writeSSEMessage(w, msg, format, log)
+					lastSeq = msg.Seq
+				}
 				flusher.Flush()
 
 			case <-ctx.Done():
@@ -60,3 +116,46 @@ func NewSSEHandler(log logger.Logger, broker *realtime.SSEBroker) http.HandlerFu
 		}
 	}
 }
+
+// replaySSE writes the channel's history after lastSeq, page by page until
+// it is caught up, and returns the last sequence written. Sequences missing
+// from history are reported with a replay_truncated event.
+func replaySSE(
+	ctx context.Context,
+	w http.ResponseWriter,
+	history realtime.History,
+	channel string,
+	lastSeq int64,
+	format string,
+	log logger.Logger,
+) (int64, error) {
+	for {
+		missed, err := history.Since(ctx, channel, lastSeq, sseReplayLimit)
+		if err != nil {
+			return lastSeq, err
+		}
+		if len(missed) > 0 && missed[0].Seq > lastSeq+1 {
+			fmt.Fprintf(w, "event: replay_truncated\ndata: {\"channel\":%q,\"requested_seq\":%d,\"oldest_seq\":%d}\n\n",
+				channel, lastSeq, missed[0].Seq)
+		}
+		for _, msg := range missed {
+			writeSSEMessage(w, msg, format, log)
+			lastSeq = msg.Seq
+		}
+		if len(missed) < sseReplayLimit {
+			return lastSeq, nil
+		}
+	}
+}
+
+func writeSSEMessage(w http.ResponseWriter, msg realtime.Message, format string, log logger.Logger) {
+	payload, err := msg.Encode(format)
+	if err != nil {
+		log.Error("failed to encode sse message", "err", err, "channel", msg.Channel, "seq", msg.Seq)
+		return
+	}
+	// Ingest rejects control characters in event types; strip line breaks
+	// anyway so history written before that check cannot inject fields.
+	eventType := strings.NewReplacer("\r", "", "\n", "").Replace(msg.EventType)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Seq, eventType, payload)
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/clienttoken"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/config"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
+)
+
+type clientTokenRequest struct {
+	// Channels narrows the token to these channels or patterns; they must
+	// lie within the key's own allowed channels. Empty inherits them.
+	Channels []string `json:"channels"`
+	TTL      string   `json:"ttl"`
+}
+
+type clientTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// NewClientTokenHandler lets a backend exchange its API key for a
+// short-lived token it can hand to a browser, which may only subscribe.
+func NewClientTokenHandler(cfg config.Config, log logger.Logger) http.HandlerFunc {
+	secret := []byte(cfg.ClientTokenSecret)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if apiKey, _ := ctx.Value(ContextKeyAPIKey).(string); apiKey == "" {
+			http.Error(w, "client tokens require an api key", http.StatusForbidden)
+			return
+		}
+		principal, _ := ctx.Value(ContextKeyPrincipal).(realtime.Principal)
+
+		var req clientTokenRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		ttl := cfg.ClientTokenTTL
+		if req.TTL != "" {
+			d, err := time.ParseDuration(req.TTL)
+			if err != nil || d <= 0 || d > cfg.ClientTokenMaxTTL {
+				http.Error(w, "invalid ttl, expected a duration up to "+cfg.ClientTokenMaxTTL.String(), http.StatusBadRequest)
+				return
+			}
+			ttl = d
+		}
+
+		channels := principal.AllowedChannels
+		if len(req.Channels) > 0 {
+			namespace := "tenant:" + principal.TenantID + ":"
+			for _, c := range req.Channels {
+				if !strings.HasPrefix(strings.TrimSuffix(c, "*"), namespace) || !channelWithin(c, principal.AllowedChannels) {
+					http.Error(w, "channel not allowed for this key: "+c, http.StatusForbidden)
+					return
+				}
+			}
+			channels = req.Channels
+		}
+
+		now := time.Now()
+		claims := clienttoken.Claims{
+			KeyID:     principal.KeyID,
+			TenantID:  principal.TenantID,
+			Channels:  channels,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		}
+		token, err := clienttoken.Sign(secret, claims)
+		if err != nil {
+			log.Error("sign client token failed", "err", err)
+			http.Error(w, "sign client token failed", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, clientTokenResponse{
+			Token:     token,
+			ExpiresAt: claims.Expiry().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// channelWithin reports whether every channel matched by pattern c is also
+// matched by one of allowed. Patterns end in "*" to match any suffix.
+func channelWithin(c string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(strings.TrimSuffix(c, "*"), prefix) {
+				return true
+			}
+		} else if a == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package gateway
+
+import "testing"
+
+func TestChannelWithin(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       string
+		allowed []string
+		want    bool
+	}{
+		{"no restriction", "tenant:t1:anything", nil, true},
+		{"exact", "tenant:t1:orders", []string{"tenant:t1:orders"}, true},
+		{"exact mismatch", "tenant:t1:orders2", []string{"tenant:t1:orders"}, false},
+		{"pattern not within exact", "tenant:t1:orders*", []string{"tenant:t1:orders"}, false},
+		{"under pattern", "tenant:t1:orders.eu", []string{"tenant:t1:orders*"}, true},
+		{"pattern prefix itself", "tenant:t1:orders", []string{"tenant:t1:orders*"}, true},
+		{"narrower pattern", "tenant:t1:orders.eu*", []string{"tenant:t1:orders*"}, true},
+		{"same pattern", "tenant:t1:orders*", []string{"tenant:t1:orders*"}, true},
+		{"wider pattern", "tenant:t1:*", []string{"tenant:t1:orders*"}, false},
+		{"outside pattern", "tenant:t1:invoices", []string{"tenant:t1:orders*"}, false},
+		{"other tenant", "tenant:t2:orders", []string{"tenant:t1:*"}, false},
+		{"any of several", "tenant:t1:invoices", []string{"tenant:t1:orders*", "tenant:t1:invoices"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := channelWithin(tt.c, tt.allowed); got != tt.want {
+				t.Errorf("channelWithin(%q, %q) = %v, want %v", tt.c, tt.allowed, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,13 +8,32 @@ import (
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/realtime"
 )
 
+// clientTokenSubprotocol is offered by browsers that pass a client token in
+// Sec-WebSocket-Protocol, e.g. new WebSocket(url, ["nexus.token", token]).
+// The server selects it, since a browser drops a connection whose response
+// names none of the offered protocols, and it keeps the token out of the
+// response.
+const clientTokenSubprotocol = "nexus.token"
+
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin:  func(r *http.Request) bool { return true },
+	Subprotocols: []string{clientTokenSubprotocol},
 }
 
-func NewWSHandler(log logger.Logger, hub *realtime.WSHub) http.HandlerFunc {
+func NewWSHandler(
+	log logger.Logger,
+	hub *realtime.WSHub,
+	history realtime.History,
+	auth realtime.ChannelAuthorizer,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tenantID, _ := r.Context().Value(ContextKeyTenantID).(string)
+		principal, _ := r.Context().Value(ContextKeyPrincipal).(realtime.Principal)
+
+		format, err := realtime.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -22,7 +41,7 @@ func NewWSHandler(log logger.Logger, hub *realtime.WSHub) http.HandlerFunc {
 			return
 		}
 
-		client := realtime.NewWSClient(conn, log, hub, tenantID)
+		client := realtime.NewWSClient(conn, log, hub, history, auth, principal, format)
 
 		go client.WritePump()
 		go client.ReadPump()
@@ -0,0 +1,152 @@
+package clienttoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("test-secret")
+	testNow    = time.Unix(1700000000, 0)
+)
+
+func testClaims() Claims {
+	return Claims{
+		KeyID:     "key-1",
+		TenantID:  "t1",
+		Channels:  []string{"tenant:t1:orders"},
+		IssuedAt:  testNow.Unix(),
+		ExpiresAt: testNow.Add(15 * time.Minute).Unix(),
+	}
+}
+
+func mustSign(t *testing.T, c Claims) string {
+	t.Helper()
+	token, err := Sign(testSecret, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	want := testClaims()
+	got, err := Verify(testSecret, mustSign(t, want), testNow)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Verify() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	token := mustSign(t, testClaims())
+	parts := strings.Split(token, ".")
+
+	widened := testClaims()
+	widened.Channels = []string{"tenant:t1:*"}
+	forged := strings.Split(mustSign(t, widened), ".")[1]
+
+	otherKey, err := Sign([]byte("other-secret"), testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"two parts", parts[0] + "." + parts[1]},
+		{"four parts", token + ".x"},
+		{"swapped payload", parts[0] + "." + forged + "." + parts[2]},
+		{"truncated signature", parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-2]},
+		{"flipped signature", parts[0] + "." + parts[1] + "." + flip(parts[2])},
+		{"other secret", otherKey},
+		{"alg none", noneHeader + "." + parts[1] + "."},
+		{"alg none keeping signature", noneHeader + "." + parts[1] + "." + parts[2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(testSecret, tt.token, testNow); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Verify() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequiresIdentity(t *testing.T) {
+	for _, c := range []Claims{
+		{TenantID: "t1", ExpiresAt: testNow.Add(time.Minute).Unix()},
+		{KeyID: "key-1", ExpiresAt: testNow.Add(time.Minute).Unix()},
+	} {
+		if _, err := Verify(testSecret, mustSign(t, c), testNow); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Verify(%+v) error = %v, want ErrInvalid", c, err)
+		}
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	c := testClaims()
+	token := mustSign(t, c)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want error
+	}{
+		{"before expiry", c.Expiry().Add(-time.Second), nil},
+		{"at expiry", c.Expiry(), ErrExpired},
+		{"after expiry", c.Expiry().Add(time.Hour), ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(testSecret, token, tt.now); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksSignatureBeforeExpiry(t *testing.T) {
+	c := testClaims()
+	parts := strings.Split(mustSign(t, c), ".")
+	tampered := parts[0] + "." + parts[1] + "." + flip(parts[2])
+	if _, err := Verify(testSecret, tampered, c.Expiry().Add(time.Hour)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() error = %v, want ErrInvalid", err)
+	}
+}
+
+func TestLooks(t *testing.T) {
+	token := mustSign(t, testClaims())
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{token, true},
+		{"nexus.token", false},
+		{"graphql-ws", false},
+		{token + ".x", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Looks(tt.s); got != tt.want {
+			t.Errorf("Looks(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+// flip changes the first character of a base64url string.
+func flip(s string) string {
+	c := byte('A')
+	if s[0] == 'A' {
+		c = 'B'
+	}
+	return string(c) + s[1:]
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -11,8 +13,47 @@ type Config struct {
 	ControlListenAddr string
 	LogLevel          string
 	DBDSN             string
+
+	ChannelHistoryRetention int
+
+	MaxEventBytes     int64
+	MaxBatchSize      int
+	MaxBatchBytes     int64
+	IdempotencyWindow time.Duration
+
+	WebhookMaxAttempts    int
+	WebhookTimeout        time.Duration
+	WebhookBackoffBase    time.Duration
+	WebhookBackoffMax     time.Duration
+	WebhookPollInterval   time.Duration
+	WebhookWorkers        int
+	WebhookMaxPerEndpoint int
+	// WebhookAllowPrivateAddresses permits webhook urls on loopback and
+	// private networks; only for local development.
+	WebhookAllowPrivateAddresses bool
+
+	RouteRefreshInterval     time.Duration
+	RedactionRefreshInterval time.Duration
+	SchemaRefreshInterval    time.Duration
+
+	APIKeyUsageFlushInterval time.Duration
+
+	// ClientTokenSecret signs browser client tokens and must be the same
+	// on every gateway; when empty the gateway picks a random one.
+	ClientTokenSecret Secret
+	ClientTokenTTL    time.Duration
+	ClientTokenMaxTTL time.Duration
+
+	// ControlAdminToken is a bootstrap superadmin bearer token for the
+	// control plane, used to create the stored admin tokens.
+	ControlAdminToken Secret
 }
 
+// Secret is a config value that is never printed.
+type Secret string
+
+func (Secret) String() string { return "[redacted]" }
+
 func Load() Config {
 	cfg := Config{
 		Env:               getEnv("APP_ENV", "local"),
@@ -20,6 +61,35 @@ func Load() Config {
 		ControlListenAddr: getEnv("CONTROL_LISTEN_ADDR", ":8081"),
 		LogLevel:          getEnv("LOG_LEVEL", "debug"),
 		DBDSN:             getEnv("DB_DSN", "file:nexus.db?_foreign_keys=on"),
+
+		ChannelHistoryRetention: getEnvInt("CHANNEL_HISTORY_RETENTION", 1000),
+
+		MaxEventBytes:     int64(getEnvInt("MAX_EVENT_BYTES", 1<<20)),
+		MaxBatchSize:      getEnvInt("MAX_BATCH_SIZE", 500),
+		MaxBatchBytes:     int64(getEnvInt("MAX_BATCH_BYTES", 5<<20)),
+		IdempotencyWindow: getEnvDuration("IDEMPOTENCY_WINDOW", 24*time.Hour),
+
+		WebhookMaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeout:        getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookBackoffBase:    getEnvDuration("WEBHOOK_BACKOFF_BASE", 2*time.Second),
+		WebhookBackoffMax:     getEnvDuration("WEBHOOK_BACKOFF_MAX", 10*time.Minute),
+		WebhookPollInterval:   getEnvDuration("WEBHOOK_POLL_INTERVAL", time.Second),
+		WebhookWorkers:        getEnvInt("WEBHOOK_WORKERS", 32),
+		WebhookMaxPerEndpoint: getEnvInt("WEBHOOK_MAX_PER_ENDPOINT", 4),
+
+		WebhookAllowPrivateAddresses: getEnvBool("WEBHOOK_ALLOW_PRIVATE_ADDRESSES", false),
+
+		RouteRefreshInterval:     getEnvDuration("ROUTE_REFRESH_INTERVAL", time.Second),
+		RedactionRefreshInterval: getEnvDuration("REDACTION_REFRESH_INTERVAL", time.Second),
+		SchemaRefreshInterval:    getEnvDuration("SCHEMA_REFRESH_INTERVAL", time.Second),
+
+		APIKeyUsageFlushInterval: getEnvDuration("API_KEY_USAGE_FLUSH_INTERVAL", 10*time.Second),
+
+		ClientTokenSecret: Secret(getEnv("CLIENT_TOKEN_SECRET", "")),
+		ClientTokenTTL:    getEnvDuration("CLIENT_TOKEN_TTL", 15*time.Minute),
+		ClientTokenMaxTTL: getEnvDuration("CLIENT_TOKEN_MAX_TTL", time.Hour),
+
+		ControlAdminToken: Secret(getEnv("CONTROL_ADMIN_TOKEN", "")),
 	}
 
 	log.Printf("config loaded: %+v\n", cfg)
@@ -33,3 +103,42 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("invalid %s=%q, must be a positive integer, using default %d\n", key, v, def)
+		return def
+	}
+	return n
+}
+
+func getEnvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("invalid %s=%q, must be a boolean, using default %t\n", key, v, def)
+		return def
+	}
+	return b
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("invalid %s=%q, must be a positive duration, using default %s\n", key, v, def)
+		return def
+	}
+	return d
+}
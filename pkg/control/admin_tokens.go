@@ -0,0 +1,212 @@
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Admin roles for the control plane.
+const (
+	// AdminRoleSuperadmin manages every tenant and the admin tokens.
+	AdminRoleSuperadmin = "superadmin"
+	// AdminRoleTenantAdmin manages one tenant.
+	AdminRoleTenantAdmin = "tenant-admin"
+	// AdminRoleReadOnly may only read, across all tenants or within one.
+	AdminRoleReadOnly = "read-only"
+)
+
+var ErrInvalidAdminRole = errors.New("invalid admin role")
+
+// AdminToken is a control plane credential. Like API keys it is stored as
+// a salted hash and looked up by its public prefix.
+type AdminToken struct {
+	ID     string
+	Name   string
+	Role   string
+	Prefix string
+	// Secret is only known when the token is created.
+	Secret string
+	// TenantID scopes a tenant-admin or read-only token to one tenant;
+	// empty means every tenant.
+	TenantID  string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// CanRead reports whether t may read tenantID's configuration.
+func (t AdminToken) CanRead(tenantID string) bool {
+	switch t.Role {
+	case AdminRoleSuperadmin:
+		return true
+	case AdminRoleTenantAdmin, AdminRoleReadOnly:
+		return t.TenantID == "" || t.TenantID == tenantID
+	}
+	return false
+}
+
+// CanWrite reports whether t may change tenantID's configuration.
+func (t AdminToken) CanWrite(tenantID string) bool {
+	switch t.Role {
+	case AdminRoleSuperadmin:
+		return true
+	case AdminRoleTenantAdmin:
+		return t.TenantID == tenantID
+	}
+	return false
+}
+
+const adminTokenSecretPrefix = "nxa_"
+
+const adminTokenColumns = `id, name, role, tenant_id, key_prefix, created_at, revoked_at`
+
+// CreateAdminToken issues a token with t's name, role and tenant. The
+// returned token carries the secret.
+func (s *Store) CreateAdminToken(ctx context.Context, t AdminToken) (*AdminToken, error) {
+	switch {
+	case t.Role == AdminRoleSuperadmin && t.TenantID != "":
+		return nil, fmt.Errorf("%w: superadmin cannot be scoped to a tenant", ErrInvalidAdminRole)
+	case t.Role == AdminRoleTenantAdmin && t.TenantID == "":
+		return nil, fmt.Errorf("%w: tenant-admin needs a tenant_id", ErrInvalidAdminRole)
+	case t.Role != AdminRoleSuperadmin && t.Role != AdminRoleTenantAdmin && t.Role != AdminRoleReadOnly:
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidAdminRole, t.Role)
+	}
+
+	t.ID = uuid.NewString()
+	t.CreatedAt = time.Now().UTC()
+	t.RevokedAt = nil
+
+	b := make([]byte, APIKeyPrefixLen/2+32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("create admin token: %w", err)
+	}
+	t.Prefix = hex.EncodeToString(b[:APIKeyPrefixLen/2])
+	t.Secret = adminTokenSecretPrefix + t.Prefix + "_" + hex.EncodeToString(b[APIKeyPrefixLen/2:])
+	salt, err := NewAPIKeySalt()
+	if err != nil {
+		return nil, fmt.Errorf("create admin token: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO admin_tokens (id, name, role, tenant_id, key_prefix, secret_hash, secret_salt, created_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.Name, t.Role, sql.NullString{String: t.TenantID, Valid: t.TenantID != ""},
+		t.Prefix, HashAPIKeySecret(salt, t.Secret), salt, t.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create admin token: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *Store) ListAdminTokens(ctx context.Context) ([]AdminToken, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+adminTokenColumns+` FROM admin_tokens ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list admin tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var out []AdminToken
+	for rows.Next() {
+		t, err := scanAdminToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan admin token: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetAdminToken(ctx context.Context, id string) (*AdminToken, error) {
+	t, err := scanAdminToken(s.db.QueryRowContext(ctx,
+		`SELECT `+adminTokenColumns+` FROM admin_tokens WHERE id = ?`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get admin token: %w", err)
+	}
+	return &t, nil
+}
+
+// RevokeAdminToken disables the token at once. Revoking twice keeps the
+// first revocation time.
+func (s *Store) RevokeAdminToken(ctx context.Context, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE admin_tokens SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`,
+		time.Now().UTC(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("revoke admin token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("revoke admin token: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetAdminTokenBySecret returns the unrevoked token matching secret, or nil.
+func (s *Store) GetAdminTokenBySecret(ctx context.Context, secret string) (*AdminToken, error) {
+	rest, ok := strings.CutPrefix(secret, adminTokenSecretPrefix)
+	if !ok || len(rest) <= APIKeyPrefixLen {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+adminTokenColumns+`, secret_hash, secret_salt
+           FROM admin_tokens
+          WHERE key_prefix = ? AND revoked_at IS NULL`,
+		rest[:APIKeyPrefixLen],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get admin token by secret: %w", err)
+	}
+	defer rows.Close()
+
+	var found *AdminToken
+	for rows.Next() {
+		var (
+			t          AdminToken
+			tenantID   sql.NullString
+			hash, salt string
+		)
+		if err := rows.Scan(
+			&t.ID, &t.Name, &t.Role, &tenantID, &t.Prefix, &t.CreatedAt, &t.RevokedAt,
+			&hash, &salt,
+		); err != nil {
+			return nil, fmt.Errorf("get admin token by secret: %w", err)
+		}
+		t.TenantID = tenantID.String
+		if subtle.ConstantTimeCompare([]byte(HashAPIKeySecret(salt, secret)), []byte(hash)) == 1 {
+			found = &t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get admin token by secret: %w", err)
+	}
+	return found, nil
+}
+
+func scanAdminToken(sc interface{ Scan(...any) error }) (AdminToken, error) {
+	var (
+		t        AdminToken
+		tenantID sql.NullString
+	)
+	if err := sc.Scan(&t.ID, &t.Name, &t.Role, &tenantID, &t.Prefix, &t.CreatedAt, &t.RevokedAt); err != nil {
+		return AdminToken{}, err
+	}
+	t.TenantID = tenantID.String
+	return t, nil
+}
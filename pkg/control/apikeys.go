@@ -0,0 +1,425 @@
+package control
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKey struct {
+	ID       string
+	TenantID string
+	// Prefix identifies the key without revealing it.
+	Prefix string
+	// Secret is only known when the key is created; the store keeps a
+	// salted hash.
+	Secret          string
+	Label           string
+	AllowedChannels []string
+	// Scopes lists what the key may do at the gateway; see ScopeAdmin and
+	// friends.
+	Scopes []string
+	// AllowedEventTypes optionally narrows which event types the key may
+	// publish. Entries are types; a trailing "*" matches any suffix.
+	AllowedEventTypes []string
+	CreatedAt         time.Time
+	ExpiresAt         *time.Time
+	RevokedAt         *time.Time
+	// LastUsedAt lags real use by up to one KeyUsage flush interval.
+	LastUsedAt *time.Time
+}
+
+// API key states as reported by Status.
+const (
+	APIKeyActive  = "active"
+	APIKeyExpired = "expired"
+	APIKeyRevoked = "revoked"
+)
+
+func (k APIKey) Status(now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return APIKeyRevoked
+	case k.ExpiresAt != nil && !k.ExpiresAt.After(now):
+		return APIKeyExpired
+	}
+	return APIKeyActive
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	return HasScope(k.Scopes, scope)
+}
+
+// ErrAPIKeyInactive is returned when rotating a revoked or expired key.
+var ErrAPIKeyInactive = errors.New("api key is revoked or expired")
+
+// API key secrets look like sk_<prefix>_<secret>. Only the prefix is
+// stored in clear, to find the key and to identify it in listings; the
+// whole secret is stored as an HMAC keyed by a per-key salt.
+const (
+	apiKeySecretPrefix = "sk_"
+	// APIKeyPrefixLen is the length of the identifying prefix after "sk_".
+	// Keys issued before hashing (sk_<uuid>) use the same span of their
+	// UUID, so they are found the same way.
+	APIKeyPrefixLen = 12
+)
+
+const apiKeyColumns = `id, tenant_id, key_prefix, label, allowed_channels, scopes, allowed_event_types,
+                       created_at, expires_at, revoked_at, last_used_at`
+
+// CreateAPIKey issues a key for k.TenantID with k's label, allowed channels,
+// scopes and optional expiry; no scopes means DefaultAPIKeyScopes. The
+// returned key carries the secret.
+func (s *Store) CreateAPIKey(ctx context.Context, k APIKey) (*APIKey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	defer tx.Rollback()
+
+	key, err := insertAPIKey(ctx, tx, k)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	return key, nil
+}
+
+func insertAPIKey(ctx context.Context, tx *sql.Tx, k APIKey) (*APIKey, error) {
+	k.ID = uuid.NewString()
+	k.CreatedAt = time.Now().UTC()
+	k.RevokedAt, k.LastUsedAt = nil, nil
+	if k.ExpiresAt != nil {
+		exp := k.ExpiresAt.UTC()
+		k.ExpiresAt = &exp
+	}
+	if len(k.Scopes) == 0 {
+		k.Scopes = DefaultAPIKeyScopes
+	}
+	if err := ValidateScopes(k.Scopes, k.AllowedEventTypes); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if k.Secret, k.Prefix, err = newAPIKeySecret(); err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	salt, err := NewAPIKeySalt()
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	channels, err := encodeStringList(k.AllowedChannels)
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	scopes, err := encodeStringList(k.Scopes)
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	eventTypes, err := encodeStringList(k.AllowedEventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO api_keys (id, tenant_id, key_prefix, secret_hash, secret_salt, label, allowed_channels,
+                               scopes, allowed_event_types, created_at, expires_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		k.ID, k.TenantID, k.Prefix, HashAPIKeySecret(salt, k.Secret), salt, k.Label, channels,
+		scopes, eventTypes, k.CreatedAt, k.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+	return &k, nil
+}
+
+// ListAPIKeys returns all of the tenant's keys, including revoked and
+// expired ones, newest first.
+func (s *Store) ListAPIKeys(ctx context.Context, tenantID string) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE tenant_id = ? ORDER BY created_at DESC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var out []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list api keys: %w", err)
+		}
+		out = append(out, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, tenantID, keyID string) (*APIKey, error) {
+	k, err := getAPIKey(ctx, s.db, tenantID, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	return k, nil
+}
+
+// RevokeAPIKey stops the key from authenticating immediately. Revoking a
+// revoked key keeps the original time; false means the key does not exist.
+func (s *Store) RevokeAPIKey(ctx context.Context, tenantID, keyID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE tenant_id = ? AND id = ?`,
+		time.Now().UTC(), tenantID, keyID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("revoke api key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("revoke api key: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RotateAPIKey issues a replacement with the same label and allowed
+// channels, expiring at expiresAt (nil for never). The old key keeps
+// working for grace, or until its own expiry if sooner. It returns nil when
+// the key does not exist and ErrAPIKeyInactive when it is revoked or
+// expired.
+func (s *Store) RotateAPIKey(ctx context.Context, tenantID, keyID string, grace time.Duration, expiresAt *time.Time) (*APIKey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("rotate api key: %w", err)
+	}
+	defer tx.Rollback()
+
+	old, err := getAPIKey(ctx, tx, tenantID, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("rotate api key: %w", err)
+	}
+	if old == nil {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	if old.Status(now) != APIKeyActive {
+		return nil, ErrAPIKeyInactive
+	}
+
+	cutoff := now.Add(grace)
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE api_keys SET expires_at = ?
+          WHERE id = ? AND (expires_at IS NULL OR expires_at > ?)`,
+		cutoff, keyID, cutoff,
+	); err != nil {
+		return nil, fmt.Errorf("rotate api key: %w", err)
+	}
+
+	key, err := insertAPIKey(ctx, tx, APIKey{
+		TenantID:          tenantID,
+		Label:             old.Label,
+		AllowedChannels:   old.AllowedChannels,
+		Scopes:            old.Scopes,
+		AllowedEventTypes: old.AllowedEventTypes,
+		ExpiresAt:         expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("rotate api key: %w", err)
+	}
+	return key, nil
+}
+
+// GetTenantByAPIKey finds the key by its prefix and checks the secret
+// against each candidate's hash in constant time. It returns nils when no
+// active key matches.
+func (s *Store) GetTenantByAPIKey(ctx context.Context, secret string) (*Tenant, *APIKey, error) {
+	prefix, ok := APIKeyPrefix(secret)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT t.id, t.name, t.created_at,
+                k.id, k.tenant_id, k.key_prefix, k.secret_hash, k.secret_salt, k.label, k.allowed_channels,
+                k.scopes, k.allowed_event_types, k.created_at, k.expires_at, k.last_used_at
+           FROM api_keys k
+           JOIN tenants t ON t.id = k.tenant_id
+          WHERE k.key_prefix = ? AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > ?)`,
+		prefix, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		found                        bool
+		t                            Tenant
+		k                            APIKey
+		channels, scopes, eventTypes sql.NullString
+	)
+	for rows.Next() {
+		var (
+			ct                                    Tenant
+			ck                                    APIKey
+			hash, salt                            string
+			rowChannels, rowScopes, rowEventTypes sql.NullString
+		)
+		if err := rows.Scan(
+			&ct.ID, &ct.Name, &ct.CreatedAt,
+			&ck.ID, &ck.TenantID, &ck.Prefix, &hash, &salt, &ck.Label, &rowChannels,
+			&rowScopes, &rowEventTypes, &ck.CreatedAt, &ck.ExpiresAt, &ck.LastUsedAt,
+		); err != nil {
+			return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+		}
+		if subtle.ConstantTimeCompare([]byte(HashAPIKeySecret(salt, secret)), []byte(hash)) == 1 {
+			found, t, k = true, ct, ck
+			channels, scopes, eventTypes = rowChannels, rowScopes, rowEventTypes
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+	}
+	if !found {
+		return nil, nil, nil
+	}
+
+	if k.AllowedChannels, err = decodeStringList(channels); err != nil {
+		return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+	}
+	if k.Scopes, err = decodeStringList(scopes); err != nil {
+		return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+	}
+	if k.AllowedEventTypes, err = decodeStringList(eventTypes); err != nil {
+		return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+	}
+	return &t, &k, nil
+}
+
+// RecordAPIKeyUse stores last-use times for many keys in one transaction.
+// A time older than the stored one is ignored.
+func (s *Store) RecordAPIKeyUse(ctx context.Context, used map[string]time.Time) error {
+	if len(used) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("record api key use: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		`UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`,
+	)
+	if err != nil {
+		return fmt.Errorf("record api key use: %w", err)
+	}
+	defer stmt.Close()
+
+	for id, at := range used {
+		if _, err := stmt.ExecContext(ctx, at, id, at); err != nil {
+			return fmt.Errorf("record api key use: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("record api key use: %w", err)
+	}
+	return nil
+}
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getAPIKey(ctx context.Context, q rowQuerier, tenantID, keyID string) (*APIKey, error) {
+	k, err := scanAPIKey(q.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE tenant_id = ? AND id = ?`,
+		tenantID, keyID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func scanAPIKey(sc interface{ Scan(...any) error }) (APIKey, error) {
+	var (
+		k                            APIKey
+		label                        sql.NullString
+		channels, scopes, eventTypes sql.NullString
+	)
+	if err := sc.Scan(
+		&k.ID, &k.TenantID, &k.Prefix, &label, &channels, &scopes, &eventTypes,
+		&k.CreatedAt, &k.ExpiresAt, &k.RevokedAt, &k.LastUsedAt,
+	); err != nil {
+		return APIKey{}, err
+	}
+	k.Label = label.String
+	var err error
+	if k.AllowedChannels, err = decodeStringList(channels); err != nil {
+		return APIKey{}, err
+	}
+	if k.Scopes, err = decodeStringList(scopes); err != nil {
+		return APIKey{}, err
+	}
+	if k.AllowedEventTypes, err = decodeStringList(eventTypes); err != nil {
+		return APIKey{}, err
+	}
+	return k, nil
+}
+
+func newAPIKeySecret() (secret, prefix string, err error) {
+	b := make([]byte, APIKeyPrefixLen/2+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generate api key: %w", err)
+	}
+	prefix = hex.EncodeToString(b[:APIKeyPrefixLen/2])
+	return apiKeySecretPrefix + prefix + "_" + hex.EncodeToString(b[APIKeyPrefixLen/2:]), prefix, nil
+}
+
+// NewAPIKeySalt returns a fresh salt for HashAPIKeySecret.
+func NewAPIKeySalt() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate api key salt: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// APIKeyPrefix returns the lookup prefix of a presented secret, or false
+// when it cannot be an API key.
+func APIKeyPrefix(secret string) (string, bool) {
+	rest, ok := strings.CutPrefix(secret, apiKeySecretPrefix)
+	if !ok || len(rest) <= APIKeyPrefixLen {
+		return "", false
+	}
+	return rest[:APIKeyPrefixLen], true
+}
+
+// HashAPIKeySecret is the stored form of a secret.
+func HashAPIKeySecret(salt, secret string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package control
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+)
+
+// KeyUsage batches api_keys.last_used_at updates. Authentication calls
+// Touch on every request, which only records the time in memory; Run
+// writes the latest time per key once per interval, so a busy key costs
+// one write per interval instead of one per request.
+type KeyUsage struct {
+	log   logger.Logger
+	store *Store
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func NewKeyUsage(log logger.Logger, store *Store) *KeyUsage {
+	return &KeyUsage{log: log, store: store, used: make(map[string]time.Time)}
+}
+
+func (u *KeyUsage) Touch(keyID string) {
+	now := time.Now().UTC()
+	u.mu.Lock()
+	u.used[keyID] = now
+	u.mu.Unlock()
+}
+
+// Flush writes the pending times. On failure they are merged back so the
+// next flush retries them.
+func (u *KeyUsage) Flush(ctx context.Context) error {
+	u.mu.Lock()
+	used := u.used
+	u.used = make(map[string]time.Time, len(used))
+	u.mu.Unlock()
+
+	if err := u.store.RecordAPIKeyUse(ctx, used); err != nil {
+		u.mu.Lock()
+		for id, at := range used {
+			if cur, ok := u.used[id]; !ok || at.After(cur) {
+				u.used[id] = at
+			}
+		}
+		u.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run flushes every interval until ctx is done, then flushes once more so
+// a clean shutdown loses nothing.
+func (u *KeyUsage) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := u.Flush(flushCtx); err != nil {
+				u.log.Error("api key usage flush failed", "err", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := u.Flush(ctx); err != nil && ctx.Err() == nil {
+				u.log.Error("api key usage flush failed", "err", err)
+			}
+		}
+	}
+}
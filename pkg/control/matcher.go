@@ -0,0 +1,103 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	MatchExact  = "EXACT"
+	MatchPrefix = "PREFIX"
+	MatchGlob   = "GLOB"
+	MatchRegex  = "REGEX"
+)
+
+var ErrInvalidRoute = errors.New("invalid route")
+
+// Matcher decides whether an event type satisfies a route's match rule.
+type Matcher interface {
+	Match(eventType string) bool
+}
+
+// CompileMatcher validates a route's match rule and returns its compiled form.
+//
+// GLOB patterns work on dot-separated segments: "*" matches exactly one
+// segment and "#" matches zero or more, so "invoice.*.failed" matches
+// "invoice.card.failed" and "order.#" matches "order" and "order.a.b".
+// REGEX patterns must match the whole event type.
+func CompileMatcher(matchType, matchValue string) (Matcher, error) {
+	if matchValue == "" {
+		return nil, fmt.Errorf("%w: empty match_value", ErrInvalidRoute)
+	}
+
+	switch strings.ToUpper(matchType) {
+	case MatchExact:
+		return exactMatcher(matchValue), nil
+	case MatchPrefix:
+		return prefixMatcher(matchValue), nil
+	case MatchGlob:
+		segments := strings.Split(matchValue, ".")
+		for _, seg := range segments {
+			if seg == "" {
+				return nil, fmt.Errorf("%w: glob %q has an empty segment", ErrInvalidRoute, matchValue)
+			}
+			if seg != "*" && seg != "#" && strings.ContainsAny(seg, "*#") {
+				return nil, fmt.Errorf("%w: glob %q: wildcards must fill a whole segment", ErrInvalidRoute, matchValue)
+			}
+		}
+		return globMatcher(segments), nil
+	case MatchRegex:
+		if _, err := regexp.Compile(matchValue); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRoute, err)
+		}
+		return regexMatcher{regexp.MustCompile(`^(?:` + matchValue + `)$`)}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown match_type %q", ErrInvalidRoute, matchType)
+}
+
+type exactMatcher string
+
+func (m exactMatcher) Match(eventType string) bool { return eventType == string(m) }
+
+type prefixMatcher string
+
+func (m prefixMatcher) Match(eventType string) bool { return strings.HasPrefix(eventType, string(m)) }
+
+type regexMatcher struct{ re *regexp.Regexp }
+
+func (m regexMatcher) Match(eventType string) bool { return m.re.MatchString(eventType) }
+
+type globMatcher []string
+
+func (m globMatcher) Match(eventType string) bool {
+	return matchSegments(m, strings.Split(eventType, "."))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(segments) == 0 {
+				return false
+			}
+		default:
+			if len(segments) == 0 || segments[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
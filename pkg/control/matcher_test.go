@@ -0,0 +1,100 @@
+package control
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		eventType string
+		want      bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.created.v2", false},
+
+		// "*" is exactly one segment.
+		{"invoice.*.failed", "invoice.card.failed", true},
+		{"invoice.*.failed", "invoice.failed", false},
+		{"invoice.*.failed", "invoice.card.sepa.failed", false},
+		{"order.*", "order", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order.created.v2", false},
+
+		// "#" is zero or more segments.
+		{"order.#", "order", true},
+		{"order.#", "order.created", true},
+		{"order.#", "order.a.b", true},
+		{"order.#", "orders.created", false},
+		{"#.failed", "failed", true},
+		{"#.failed", "invoice.card.failed", true},
+		{"#.failed", "invoice.failed.retry", false},
+		{"order.#.v2", "order.v2", true},
+		{"order.#.v2", "order.created.eu.v2", true},
+		{"order.#.v2", "order.created.v3", false},
+		{"#", "anything.at.all", true},
+		{"#.#", "a", true},
+		{"#.*", "a.b.c", true},
+		{"#.*", "", true},
+		{"*.#", "a", true},
+		{"a.#.b.#.c", "a.x.b.y.z.c", true},
+		{"a.#.b.#.c", "a.x.c", false},
+	}
+	for _, tt := range tests {
+		m, err := CompileMatcher(MatchGlob, tt.pattern)
+		if err != nil {
+			t.Fatalf("CompileMatcher(%q) error = %v", tt.pattern, err)
+		}
+		if got := m.Match(tt.eventType); got != tt.want {
+			t.Errorf("glob %q Match(%q) = %v, want %v", tt.pattern, tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestCompileMatcherRejects(t *testing.T) {
+	tests := []struct {
+		matchType, matchValue string
+	}{
+		{MatchExact, ""},
+		{MatchGlob, "order..created"},
+		{MatchGlob, ".order"},
+		{MatchGlob, "order.cre*"},
+		{MatchGlob, "order.#x"},
+		{MatchGlob, "order.**"},
+		{MatchRegex, "order.(created"},
+		{"FUZZY", "order"},
+	}
+	for _, tt := range tests {
+		if _, err := CompileMatcher(tt.matchType, tt.matchValue); !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("CompileMatcher(%q, %q) error = %v, want ErrInvalidRoute", tt.matchType, tt.matchValue, err)
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		matchType, matchValue, eventType string
+		want                             bool
+	}{
+		{MatchExact, "order.created", "order.created", true},
+		{MatchExact, "order.created", "order.created2", false},
+		{"exact", "order.created", "order.created", true},
+		{MatchPrefix, "order.", "order.created", true},
+		{MatchPrefix, "order.", "orders.created", false},
+		{MatchRegex, `order\.(created|updated)`, "order.updated", true},
+		{MatchRegex, `order\.(created|updated)`, "order.updated.v2", false},
+		{MatchRegex, `created`, "order.created", false},
+		{MatchRegex, `a|b`, "a", true},
+		{MatchRegex, `a|b`, "ab", false},
+	}
+	for _, tt := range tests {
+		m, err := CompileMatcher(tt.matchType, tt.matchValue)
+		if err != nil {
+			t.Fatalf("CompileMatcher(%q, %q) error = %v", tt.matchType, tt.matchValue, err)
+		}
+		if got := m.Match(tt.eventType); got != tt.want {
+			t.Errorf("%s %q Match(%q) = %v, want %v", tt.matchType, tt.matchValue, tt.eventType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RedactionPolicy is a tenant's stored redact.Policy. Salt keys the hashes
+// of hash-mode redactions and never leaves the server.
+type RedactionPolicy struct {
+	TenantID  string
+	Policy    json.RawMessage
+	Salt      string
+	UpdatedAt time.Time
+}
+
+// GetRedactionPolicy returns nil, nil when the tenant has no policy.
+func (s *Store) GetRedactionPolicy(ctx context.Context, tenantID string) (*RedactionPolicy, error) {
+	p := RedactionPolicy{TenantID: tenantID}
+	var policy string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT policy, salt, updated_at FROM redaction_policies WHERE tenant_id = ?`,
+		tenantID,
+	).Scan(&policy, &p.Salt, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get redaction policy: %w", err)
+	}
+	p.Policy = json.RawMessage(policy)
+	return &p, nil
+}
+
+// SetRedactionPolicy stores the tenant's policy, keeping the existing salt
+// so hashes stay stable across policy edits.
+func (s *Store) SetRedactionPolicy(ctx context.Context, tenantID string, policy json.RawMessage) (*RedactionPolicy, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("set redaction policy: %w", err)
+	}
+	now := time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO redaction_policies (tenant_id, policy, salt, updated_at)
+         VALUES (?, ?, ?, ?)
+         ON CONFLICT(tenant_id) DO UPDATE
+            SET policy = excluded.policy, updated_at = excluded.updated_at`,
+		tenantID, string(policy), hex.EncodeToString(b), now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("set redaction policy: %w", err)
+	}
+	return s.GetRedactionPolicy(ctx, tenantID)
+}
+
+// DeleteRedactionPolicy reports whether the tenant had a policy.
+func (s *Store) DeleteRedactionPolicy(ctx context.Context, tenantID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM redaction_policies WHERE tenant_id = ?`,
+		tenantID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete redaction policy: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete redaction policy: %w", err)
+	}
+	return n > 0, nil
+}
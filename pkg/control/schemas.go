@@ -0,0 +1,228 @@
+package control
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventSchema is one registered version of the JSON Schema for a tenant's
+// event type. At most one version per event type is active; events of a
+// type without an active version are not validated.
+type EventSchema struct {
+	TenantID  string
+	EventType string
+	Version   int
+	Schema    json.RawMessage
+	Active    bool
+	CreatedAt time.Time
+}
+
+// Schema modes decide what happens to an event that fails validation.
+const (
+	SchemaModeReject = "reject" // refuse the event with the field errors
+	SchemaModeTag    = "tag"    // accept it, marked invalid in its status
+)
+
+var ErrInvalidSchemaMode = errors.New("invalid schema mode")
+
+const schemaColumns = `tenant_id, event_type, version, schema, active, created_at`
+
+// RegisterSchema stores schema as the next version for the event type and
+// makes it the active one.
+func (s *Store) RegisterSchema(ctx context.Context, tenantID, eventType string, schema json.RawMessage) (*EventSchema, error) {
+	es := EventSchema{
+		TenantID:  tenantID,
+		EventType: eventType,
+		Schema:    schema,
+		Active:    true,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("register schema: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM event_schemas WHERE tenant_id = ? AND event_type = ?`,
+		tenantID, eventType,
+	).Scan(&es.Version); err != nil {
+		return nil, fmt.Errorf("register schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE event_schemas SET active = 0 WHERE tenant_id = ? AND event_type = ?`,
+		tenantID, eventType,
+	); err != nil {
+		return nil, fmt.Errorf("register schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO event_schemas (tenant_id, event_type, version, schema, active, created_at)
+         VALUES (?, ?, ?, ?, 1, ?)`,
+		tenantID, eventType, es.Version, string(schema), es.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("register schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("register schema: %w", err)
+	}
+	return &es, nil
+}
+
+// ListSchemas returns every version registered by the tenant, newest first
+// within each event type. A non-empty eventType restricts the list to it.
+func (s *Store) ListSchemas(ctx context.Context, tenantID, eventType string) ([]EventSchema, error) {
+	query := `SELECT ` + schemaColumns + ` FROM event_schemas WHERE tenant_id = ?`
+	args := []any{tenantID}
+	if eventType != "" {
+		query += ` AND event_type = ?`
+		args = append(args, eventType)
+	}
+	query += ` ORDER BY event_type ASC, version DESC`
+	return s.querySchemas(ctx, "list schemas", query, args...)
+}
+
+// ActiveSchemas returns the active version of each of the tenant's event
+// types.
+func (s *Store) ActiveSchemas(ctx context.Context, tenantID string) ([]EventSchema, error) {
+	return s.querySchemas(ctx, "list active schemas",
+		`SELECT `+schemaColumns+` FROM event_schemas WHERE tenant_id = ? AND active = 1`,
+		tenantID,
+	)
+}
+
+func (s *Store) GetSchema(ctx context.Context, tenantID, eventType string, version int) (*EventSchema, error) {
+	es, err := scanSchema(s.db.QueryRowContext(ctx,
+		`SELECT `+schemaColumns+` FROM event_schemas
+          WHERE tenant_id = ? AND event_type = ? AND version = ?`,
+		tenantID, eventType, version,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get schema: %w", err)
+	}
+	return &es, nil
+}
+
+// ActivateSchema makes version the event type's active schema, e.g. to roll
+// back to an earlier one. It reports false when the version does not exist.
+func (s *Store) ActivateSchema(ctx context.Context, tenantID, eventType string, version int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("activate schema: %w", err)
+	}
+	defer tx.Rollback()
+
+	var found bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM event_schemas
+                         WHERE tenant_id = ? AND event_type = ? AND version = ?)`,
+		tenantID, eventType, version,
+	).Scan(&found); err != nil {
+		return false, fmt.Errorf("activate schema: %w", err)
+	}
+	if !found {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE event_schemas SET active = (version = ?) WHERE tenant_id = ? AND event_type = ?`,
+		version, tenantID, eventType,
+	); err != nil {
+		return false, fmt.Errorf("activate schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("activate schema: %w", err)
+	}
+	return true, nil
+}
+
+// DeactivateSchema turns validation off for the event type while keeping
+// its versions. It reports whether a version was active.
+func (s *Store) DeactivateSchema(ctx context.Context, tenantID, eventType string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE event_schemas SET active = 0 WHERE tenant_id = ? AND event_type = ? AND active = 1`,
+		tenantID, eventType,
+	)
+	if err != nil {
+		return false, fmt.Errorf("deactivate schema: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("deactivate schema: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetSchemaMode returns "" for an unknown tenant.
+func (s *Store) GetSchemaMode(ctx context.Context, tenantID string) (string, error) {
+	var mode string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT schema_mode FROM tenants WHERE id = ?`,
+		tenantID,
+	).Scan(&mode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("get schema mode: %w", err)
+	}
+	return mode, nil
+}
+
+// SetSchemaMode reports false for an unknown tenant.
+func (s *Store) SetSchemaMode(ctx context.Context, tenantID, mode string) (bool, error) {
+	if mode != SchemaModeReject && mode != SchemaModeTag {
+		return false, fmt.Errorf("%w: mode must be %s or %s, got %q", ErrInvalidSchemaMode, SchemaModeReject, SchemaModeTag, mode)
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE tenants SET schema_mode = ? WHERE id = ?`,
+		mode, tenantID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("set schema mode: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("set schema mode: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *Store) querySchemas(ctx context.Context, op, query string, args ...any) ([]EventSchema, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var out []EventSchema
+	for rows.Next() {
+		es, err := scanSchema(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		out = append(out, es)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return out, nil
+}
+
+func scanSchema(sc interface{ Scan(...any) error }) (EventSchema, error) {
+	var (
+		es     EventSchema
+		schema string
+	)
+	if err := sc.Scan(&es.TenantID, &es.EventType, &es.Version, &schema, &es.Active, &es.CreatedAt); err != nil {
+		return EventSchema{}, err
+	}
+	es.Schema = json.RawMessage(schema)
+	return es, nil
+}
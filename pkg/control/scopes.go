@@ -0,0 +1,66 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// API key scopes. The gateway only lets a key call endpoints its scopes
+// cover; admin covers everything.
+const (
+	ScopeEventsPublish     = "events:publish"
+	ScopeChannelsSubscribe = "channels:subscribe"
+	ScopeAdmin             = "admin"
+)
+
+// DefaultAPIKeyScopes is what keys created without scopes get. Keys
+// created before scopes existed were migrated to the same set.
+var DefaultAPIKeyScopes = []string{ScopeEventsPublish, ScopeChannelsSubscribe}
+
+var ErrInvalidScope = errors.New("invalid api key scope")
+
+// ValidateScopes rejects unknown scopes and malformed event type patterns.
+func ValidateScopes(scopes, eventTypes []string) error {
+	for _, s := range scopes {
+		switch s {
+		case ScopeEventsPublish, ScopeChannelsSubscribe, ScopeAdmin:
+		default:
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidScope, s)
+		}
+	}
+	for _, p := range eventTypes {
+		if p == "" || strings.Contains(strings.TrimSuffix(p, "*"), "*") {
+			return fmt.Errorf("%w: event type pattern %q must be a type or a prefix ending in *", ErrInvalidScope, p)
+		}
+	}
+	return nil
+}
+
+// HasScope reports whether scopes grant scope.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPatterns reports whether s matches one of patterns, where a
+// trailing "*" matches any suffix. No patterns means no restriction.
+func MatchPatterns(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(s, prefix) {
+				return true
+			}
+		} else if p == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SigningSecret struct {
+	ID        string
+	TenantID  string
+	Secret    string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+}
+
+func newSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// RotateSigningSecret issues a new signing secret for the tenant. Secrets
+// that were active keep signing deliveries for grace so receivers can roll
+// over without rejecting traffic.
+func (s *Store) RotateSigningSecret(ctx context.Context, tenantID string, grace time.Duration) (*SigningSecret, error) {
+	secret, err := newSigningSecret()
+	if err != nil {
+		return nil, fmt.Errorf("rotate signing secret: %w", err)
+	}
+	id := uuid.NewString()
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("rotate signing secret: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE signing_secrets
+            SET expires_at = ?
+          WHERE tenant_id = ? AND (expires_at IS NULL OR expires_at > ?)`,
+		now.Add(grace), tenantID, now.Add(grace),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("rotate signing secret: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO signing_secrets (id, tenant_id, secret, created_at) VALUES (?, ?, ?, ?)`,
+		id, tenantID, secret, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("rotate signing secret: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("rotate signing secret: %w", err)
+	}
+
+	return &SigningSecret{
+		ID:        id,
+		TenantID:  tenantID,
+		Secret:    secret,
+		CreatedAt: now,
+	}, nil
+}
+
+func (s *Store) ListActiveSigningSecrets(ctx context.Context, tenantID string) ([]SigningSecret, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, tenant_id, secret, created_at, expires_at
+           FROM signing_secrets
+          WHERE tenant_id = ? AND (expires_at IS NULL OR expires_at > ?)
+          ORDER BY created_at DESC`,
+		tenantID, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list signing secrets: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SigningSecret
+	for rows.Next() {
+		var ss SigningSecret
+		if err := rows.Scan(&ss.ID, &ss.TenantID, &ss.Secret, &ss.CreatedAt, &ss.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan signing secret: %w", err)
+		}
+		out = append(out, ss)
+	}
+	return out, rows.Err()
+}
+
+// SigningSecrets returns the raw secrets currently used to sign the tenant's
+// outbound deliveries, newest first.
+func (s *Store) SigningSecrets(ctx context.Context, tenantID string) ([]string, error) {
+	active, err := s.ListActiveSigningSecrets(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(active))
+	for _, ss := range active {
+		out = append(out, ss.Secret)
+	}
+	return out, nil
+}
@@ -3,6 +3,8 @@ package control
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -24,32 +26,58 @@ type Tenant struct {
 	CreatedAt time.Time
 }
 
-type APIKey struct {
-	ID        string
-	TenantID  string
-	Secret    string
-	Label     string
-	CreatedAt time.Time
-}
-
 type Route struct {
 	ID            string
 	TenantID      string
 	MatchType     string
 	MatchValue    string
 	TargetChannel string
-	CreatedAt     time.Time
+	Filter        json.RawMessage
+	Transform     json.RawMessage
+	Enabled       bool
+	// Priority orders evaluation, lowest first; ties go to the older route.
+	Priority int
+	// StopProcessing ends evaluation once this route matches.
+	StopProcessing bool
+	Version        int64 // bumped on every update; the API exposes it as the ETag
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
+var ErrRouteVersionConflict = errors.New("route was modified concurrently")
+
+const routeColumns = `id, tenant_id, match_type, match_value, target_channel, filter, transform,
+                       enabled, priority, stop_processing, version, created_at, updated_at`
+
 func (s *Store) CreateTenant(ctx context.Context, name string) (*Tenant, error) {
 	id := uuid.NewString()
 	now := time.Now().UTC()
 
-	_, err := s.db.ExecContext(ctx,
+	secret, err := newSigningSecret()
+	if err != nil {
+		return nil, fmt.Errorf("create tenant: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create tenant: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
 		`INSERT INTO tenants (id, name, created_at) VALUES (?, ?, ?)`,
 		id, name, now,
-	)
-	if err != nil {
+	); err != nil {
+		return nil, fmt.Errorf("create tenant: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO signing_secrets (id, tenant_id, secret, created_at) VALUES (?, ?, ?, ?)`,
+		uuid.NewString(), id, secret, now,
+	); err != nil {
+		return nil, fmt.Errorf("create tenant signing secret: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("create tenant: %w", err)
 	}
 
@@ -80,87 +108,62 @@ func (s *Store) ListTenants(ctx context.Context) ([]Tenant, error) {
 	return out, rows.Err()
 }
 
-func (s *Store) CreateAPIKey(ctx context.Context, tenantID, label string) (*APIKey, error) {
-	id := uuid.NewString()
-	secret := "sk_" + uuid.NewString()
-	now := time.Now().UTC()
-
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO api_keys (id, tenant_id, secret, label, created_at)
-         VALUES (?, ?, ?, ?, ?)`,
-		id, tenantID, secret, label, now,
-	)
+func (s *Store) GetTenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	var t Tenant
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, name, created_at FROM tenants WHERE id = ?`, tenantID,
+	).Scan(&t.ID, &t.Name, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("create api key: %w", err)
+		return nil, fmt.Errorf("get tenant: %w", err)
 	}
-
-	return &APIKey{
-		ID:        id,
-		TenantID:  tenantID,
-		Secret:    secret,
-		Label:     label,
-		CreatedAt: now,
-	}, nil
+	return &t, nil
 }
 
-func (s *Store) GetTenantByAPIKey(ctx context.Context, secret string) (*Tenant, *APIKey, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT t.id, t.name, t.created_at,
-                k.id, k.tenant_id, k.secret, k.label, k.created_at
-           FROM api_keys k
-           JOIN tenants t ON t.id = k.tenant_id
-          WHERE k.secret = ?`,
-		secret,
-	)
-
-	var t Tenant
-	var k APIKey
-	if err := row.Scan(
-		&t.ID, &t.Name, &t.CreatedAt,
-		&k.ID, &k.TenantID, &k.Secret, &k.Label, &k.CreatedAt,
-	); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil, nil
-		}
-		return nil, nil, fmt.Errorf("get tenant by api key: %w", err)
+func (s *Store) CreateRoute(ctx context.Context, r Route) (*Route, error) {
+	r.MatchType = strings.ToUpper(r.MatchType)
+	if _, err := CompileMatcher(r.MatchType, r.MatchValue); err != nil {
+		return nil, err
 	}
-	return &t, &k, nil
-}
 
-func (s *Store) CreateRoute(ctx context.Context, tenantID, matchType, matchValue, targetChannel string) (*Route, error) {
-	matchType = strings.ToUpper(matchType)
-	if matchType != "EXACT" && matchType != "PREFIX" {
-		return nil, fmt.Errorf("invalid match_type: %s", matchType)
-	}
+	r.ID = uuid.NewString()
+	r.Version = 1
+	r.CreatedAt = time.Now().UTC()
+	r.UpdatedAt = r.CreatedAt
 
-	id := uuid.NewString()
-	now := time.Now().UTC()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create route: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO routes (id, tenant_id, match_type, match_value, target_channel, created_at)
-         VALUES (?, ?, ?, ?, ?, ?)`,
-		id, tenantID, matchType, matchValue, targetChannel, now,
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO routes (id, tenant_id, match_type, match_value, target_channel, filter, transform,
+                             enabled, priority, stop_processing, version, created_at, updated_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.TenantID, r.MatchType, r.MatchValue, r.TargetChannel, nullableJSON(r.Filter),
+		nullableJSON(r.Transform), r.Enabled, r.Priority, r.StopProcessing, r.Version, r.CreatedAt, r.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("create route: %w", err)
 	}
-
-	return &Route{
-		ID:            id,
-		TenantID:      tenantID,
-		MatchType:     matchType,
-		MatchValue:    matchValue,
-		TargetChannel: targetChannel,
-		CreatedAt:     now,
-	}, nil
+	if err := bumpRouteVersion(ctx, tx, r.TenantID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("create route: %w", err)
+	}
+	return &r, nil
 }
 
 func (s *Store) ListRoutes(ctx context.Context, tenantID string) ([]Route, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, tenant_id, match_type, match_value, target_channel, created_at
+		`SELECT `+routeColumns+`
            FROM routes
           WHERE tenant_id = ?
-          ORDER BY created_at ASC`,
+          ORDER BY priority ASC, created_at ASC`,
 		tenantID,
 	)
 	if err != nil {
@@ -170,8 +173,8 @@ func (s *Store) ListRoutes(ctx context.Context, tenantID string) ([]Route, error
 
 	var out []Route
 	for rows.Next() {
-		var r Route
-		if err := rows.Scan(&r.ID, &r.TenantID, &r.MatchType, &r.MatchValue, &r.TargetChannel, &r.CreatedAt); err != nil {
+		r, err := scanRoute(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan route: %w", err)
 		}
 		out = append(out, r)
@@ -179,11 +182,129 @@ func (s *Store) ListRoutes(ctx context.Context, tenantID string) ([]Route, error
 	return out, rows.Err()
 }
 
+// GetRoute returns nil, nil when the tenant has no such route.
+func (s *Store) GetRoute(ctx context.Context, tenantID, routeID string) (*Route, error) {
+	r, err := scanRoute(s.db.QueryRowContext(ctx,
+		`SELECT `+routeColumns+`
+           FROM routes
+          WHERE id = ? AND tenant_id = ?`,
+		routeID, tenantID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get route: %w", err)
+	}
+	return &r, nil
+}
+
+// UpdateRoute replaces every editable field of a route provided it is
+// still at version ifVersion. It returns nil, nil when the route does not
+// exist and ErrRouteVersionConflict when it has moved on.
+func (s *Store) UpdateRoute(ctx context.Context, r Route, ifVersion int64) (*Route, error) {
+	r.MatchType = strings.ToUpper(r.MatchType)
+	if _, err := CompileMatcher(r.MatchType, r.MatchValue); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update route: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	res, err := tx.ExecContext(ctx,
+		`UPDATE routes
+            SET match_type = ?, match_value = ?, target_channel = ?, filter = ?, transform = ?,
+                enabled = ?, priority = ?, stop_processing = ?, version = version + 1, updated_at = ?
+          WHERE id = ? AND tenant_id = ? AND version = ?`,
+		r.MatchType, r.MatchValue, r.TargetChannel, nullableJSON(r.Filter), nullableJSON(r.Transform), r.Enabled,
+		r.Priority, r.StopProcessing, now, r.ID, r.TenantID, ifVersion,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update route: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("update route: %w", err)
+	} else if n == 0 {
+		return nil, routeWriteMiss(ctx, tx, r.TenantID, r.ID)
+	}
+
+	if err := bumpRouteVersion(ctx, tx, r.TenantID); err != nil {
+		return nil, err
+	}
+	updated, err := scanRoute(tx.QueryRowContext(ctx,
+		`SELECT `+routeColumns+` FROM routes WHERE id = ?`, r.ID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("update route: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("update route: %w", err)
+	}
+	return &updated, nil
+}
+
+// DeleteRoute removes a route still at version ifVersion. It reports false
+// when the route does not exist and ErrRouteVersionConflict when it has
+// moved on.
+func (s *Store) DeleteRoute(ctx context.Context, tenantID, routeID string, ifVersion int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("delete route: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM routes WHERE id = ? AND tenant_id = ? AND version = ?`,
+		routeID, tenantID, ifVersion,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete route: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, fmt.Errorf("delete route: %w", err)
+	} else if n == 0 {
+		if err := routeWriteMiss(ctx, tx, tenantID, routeID); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	if err := bumpRouteVersion(ctx, tx, tenantID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("delete route: %w", err)
+	}
+	return true, nil
+}
+
+// routeWriteMiss explains a conditional write that matched no row: the route
+// either does not exist (nil) or is at another version.
+func routeWriteMiss(ctx context.Context, tx *sql.Tx, tenantID, routeID string) error {
+	var exists int
+	err := tx.QueryRowContext(ctx,
+		`SELECT 1 FROM routes WHERE id = ? AND tenant_id = ?`,
+		routeID, tenantID,
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("check route: %w", err)
+	}
+	return ErrRouteVersionConflict
+}
+
 func (s *Store) FindRoutesForEvent(ctx context.Context, tenantID, eventType string) ([]Route, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, tenant_id, match_type, match_value, target_channel, created_at
+		`SELECT `+routeColumns+`
            FROM routes
-          WHERE tenant_id = ?`,
+          WHERE tenant_id = ? AND enabled = 1
+          ORDER BY priority ASC, created_at ASC`,
 		tenantID,
 	)
 	if err != nil {
@@ -193,21 +314,189 @@ func (s *Store) FindRoutesForEvent(ctx context.Context, tenantID, eventType stri
 
 	var matched []Route
 	for rows.Next() {
-		var r Route
-		if err := rows.Scan(&r.ID, &r.TenantID, &r.MatchType, &r.MatchValue, &r.TargetChannel, &r.CreatedAt); err != nil {
+		r, err := scanRoute(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan route: %w", err)
 		}
 
-		switch r.MatchType {
-		case "EXACT":
-			if eventType == r.MatchValue {
-				matched = append(matched, r)
-			}
-		case "PREFIX":
-			if strings.HasPrefix(eventType, r.MatchValue) {
-				matched = append(matched, r)
-			}
+		m, err := CompileMatcher(r.MatchType, r.MatchValue)
+		if err != nil {
+			return nil, fmt.Errorf("find routes: route %s: %w", r.ID, err)
+		}
+		if m.Match(eventType) {
+			matched = append(matched, r)
 		}
 	}
 	return matched, rows.Err()
 }
+
+// RouteVersions returns the current routing version of every tenant that has
+// ever changed its routes. Caches compare these to detect stale tables.
+func (s *Store) RouteVersions(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tenant_id, version FROM route_versions`)
+	if err != nil {
+		return nil, fmt.Errorf("list route versions: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]int64)
+	for rows.Next() {
+		var tenantID string
+		var version int64
+		if err := rows.Scan(&tenantID, &version); err != nil {
+			return nil, fmt.Errorf("scan route version: %w", err)
+		}
+		out[tenantID] = version
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) RouteVersion(ctx context.Context, tenantID string) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT version FROM route_versions WHERE tenant_id = ?`,
+		tenantID,
+	).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("get route version: %w", err)
+	}
+	return version, nil
+}
+
+const (
+	FallbackDefault = "default" // the tenant's default channel
+	FallbackChannel = "channel" // a configured channel or webhook target
+	FallbackDrop    = "drop"
+)
+
+var ErrInvalidFallback = errors.New("invalid routing fallback")
+
+// RoutingFallback decides where an event goes when no route matches it.
+type RoutingFallback struct {
+	Mode    string
+	Channel string
+}
+
+// GetRoutingFallback returns nil, nil for an unknown tenant.
+func (s *Store) GetRoutingFallback(ctx context.Context, tenantID string) (*RoutingFallback, error) {
+	var fb RoutingFallback
+	var channel sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT fallback_mode, fallback_channel FROM tenants WHERE id = ?`,
+		tenantID,
+	).Scan(&fb.Mode, &channel)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get routing fallback: %w", err)
+	}
+	fb.Channel = channel.String
+	return &fb, nil
+}
+
+// SetRoutingFallback reports false for an unknown tenant.
+func (s *Store) SetRoutingFallback(ctx context.Context, tenantID string, fb RoutingFallback) (bool, error) {
+	switch fb.Mode {
+	case FallbackDefault, FallbackDrop:
+		fb.Channel = ""
+	case FallbackChannel:
+		if fb.Channel == "" {
+			return false, fmt.Errorf("%w: mode %q needs a channel", ErrInvalidFallback, fb.Mode)
+		}
+	default:
+		return false, fmt.Errorf("%w: unknown mode %q", ErrInvalidFallback, fb.Mode)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("set routing fallback: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE tenants SET fallback_mode = ?, fallback_channel = ? WHERE id = ?`,
+		fb.Mode, sql.NullString{String: fb.Channel, Valid: fb.Channel != ""}, tenantID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("set routing fallback: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, fmt.Errorf("set routing fallback: %w", err)
+	} else if n == 0 {
+		return false, nil
+	}
+
+	// The fallback is part of the compiled routing table.
+	if err := bumpRouteVersion(ctx, tx, tenantID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("set routing fallback: %w", err)
+	}
+	return true, nil
+}
+
+func bumpRouteVersion(ctx context.Context, tx *sql.Tx, tenantID string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO route_versions (tenant_id, version) VALUES (?, 1)
+         ON CONFLICT(tenant_id) DO UPDATE SET version = version + 1`,
+		tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("bump route version: %w", err)
+	}
+	return nil
+}
+
+func encodeStringList(v []string) (sql.NullString, error) {
+	if len(v) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("encode list: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func decodeStringList(v sql.NullString) ([]string, error) {
+	if !v.Valid || v.String == "" {
+		return nil, nil
+	}
+	var out []string
+	if err := json.Unmarshal([]byte(v.String), &out); err != nil {
+		return nil, fmt.Errorf("decode list: %w", err)
+	}
+	return out, nil
+}
+
+func scanRoute(sc interface{ Scan(...any) error }) (Route, error) {
+	var r Route
+	var filter, transform sql.NullString
+	var updatedAt sql.NullTime
+	if err := sc.Scan(
+		&r.ID, &r.TenantID, &r.MatchType, &r.MatchValue, &r.TargetChannel,
+		&filter, &transform, &r.Enabled, &r.Priority, &r.StopProcessing, &r.Version, &r.CreatedAt, &updatedAt,
+	); err != nil {
+		return Route{}, err
+	}
+	if filter.Valid && filter.String != "" {
+		r.Filter = json.RawMessage(filter.String)
+	}
+	if transform.Valid && transform.String != "" {
+		r.Transform = json.RawMessage(transform.String)
+	}
+	r.UpdatedAt = r.CreatedAt
+	if updatedAt.Valid {
+		r.UpdatedAt = updatedAt.Time
+	}
+	return r, nil
+}
+
+func nullableJSON(v json.RawMessage) sql.NullString {
+	if len(v) == 0 || string(v) == "null" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(v), Valid: true}
+}
@@ -0,0 +1,81 @@
+package control
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEndpoint struct {
+	ID          string
+	TenantID    string
+	URL         string
+	Description string
+	CreatedAt   time.Time
+}
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, tenantID, url, description string) (*WebhookEndpoint, error) {
+	id := uuid.NewString()
+	now := time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_endpoints (id, tenant_id, url, description, created_at)
+         VALUES (?, ?, ?, ?, ?)`,
+		id, tenantID, url, description, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook endpoint: %w", err)
+	}
+
+	return &WebhookEndpoint{
+		ID:          id,
+		TenantID:    tenantID,
+		URL:         url,
+		Description: description,
+		CreatedAt:   now,
+	}, nil
+}
+
+func (s *Store) ListWebhookEndpoints(ctx context.Context, tenantID string) ([]WebhookEndpoint, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, tenant_id, url, description, created_at
+           FROM webhook_endpoints
+          WHERE tenant_id = ?
+          ORDER BY created_at ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var out []WebhookEndpoint
+	for rows.Next() {
+		var e WebhookEndpoint
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.URL, &e.Description, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan webhook endpoint: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetWebhookEndpoint(ctx context.Context, tenantID, id string) (*WebhookEndpoint, error) {
+	var e WebhookEndpoint
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, tenant_id, url, description, created_at
+           FROM webhook_endpoints
+          WHERE tenant_id = ? AND id = ?`,
+		tenantID, id,
+	).Scan(&e.ID, &e.TenantID, &e.URL, &e.Description, &e.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get webhook endpoint: %w", err)
+	}
+	return &e, nil
+}
@@ -0,0 +1,206 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventsContentType marks a structured-mode request body.
+	CloudEventsContentType = "application/cloudevents+json"
+	// CloudEventsBatchContentType marks a JSON array of structured events.
+	CloudEventsBatchContentType = "application/cloudevents-batch+json"
+)
+
+var ErrInvalidCloudEvent = errors.New("invalid cloudevent")
+
+// CloudEvents context attributes without a place of their own in the
+// envelope are kept in SourceInfo.Extra under these keys.
+const (
+	ExtraCloudEventSource     = "ce_source"
+	ExtraCloudEventSubject    = "ce_subject"
+	ExtraCloudEventTime       = "ce_time"
+	ExtraCloudEventDataSchema = "ce_dataschema"
+)
+
+var extensionNameRe = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// cloudEventCore lists the attributes that are not extensions.
+var cloudEventCore = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true,
+	"subject": true, "time": true, "datacontenttype": true, "dataschema": true,
+	"data": true, "data_base64": true,
+}
+
+// ParseStructuredCloudEvent maps a structured-mode CloudEvent onto an
+// IngestRequest.
+func ParseStructuredCloudEvent(raw []byte) (IngestRequest, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return IngestRequest{}, fmt.Errorf("%w: %v", ErrInvalidCloudEvent, err)
+	}
+	if _, ok := fields["data_base64"]; ok {
+		return IngestRequest{}, fmt.Errorf("%w: data_base64 is not supported, data must be a JSON object", ErrInvalidCloudEvent)
+	}
+
+	attrs := make(map[string]any, len(fields))
+	for name, v := range fields {
+		if name == "data" {
+			continue
+		}
+		var val any
+		if err := json.Unmarshal(v, &val); err != nil {
+			return IngestRequest{}, fmt.Errorf("%w: attribute %s: %v", ErrInvalidCloudEvent, name, err)
+		}
+		attrs[name] = val
+	}
+	return cloudEventRequest(attrs, fields["data"])
+}
+
+// ParseBinaryCloudEvent maps a binary-mode CloudEvent onto an IngestRequest.
+// attrs holds the ce-* headers without their prefix; the body is the data.
+func ParseBinaryCloudEvent(attrs map[string]string, contentType string, body []byte) (IngestRequest, error) {
+	a := make(map[string]any, len(attrs)+1)
+	for name, v := range attrs {
+		a[name] = v
+	}
+	if contentType != "" {
+		a["datacontenttype"] = contentType
+	}
+	return cloudEventRequest(a, body)
+}
+
+func cloudEventRequest(attrs map[string]any, data []byte) (IngestRequest, error) {
+	str := func(name string) (string, error) {
+		v, ok := attrs[name]
+		if !ok {
+			return "", nil
+		}
+		s, isStr := v.(string)
+		if !isStr {
+			return "", fmt.Errorf("%w: %s must be a string", ErrInvalidCloudEvent, name)
+		}
+		return s, nil
+	}
+
+	var req IngestRequest
+	values := make(map[string]string, len(cloudEventCore))
+	for name := range cloudEventCore {
+		s, err := str(name)
+		if err != nil {
+			return IngestRequest{}, err
+		}
+		values[name] = s
+	}
+
+	if values["specversion"] != CloudEventsSpecVersion {
+		return IngestRequest{}, fmt.Errorf("%w: specversion must be %q", ErrInvalidCloudEvent, CloudEventsSpecVersion)
+	}
+	for _, name := range []string{"id", "source", "type"} {
+		if values[name] == "" {
+			return IngestRequest{}, fmt.Errorf("%w: %s is required", ErrInvalidCloudEvent, name)
+		}
+	}
+	if t := values["time"]; t != "" {
+		if _, err := time.Parse(time.RFC3339Nano, t); err != nil {
+			return IngestRequest{}, fmt.Errorf("%w: time must be RFC 3339", ErrInvalidCloudEvent)
+		}
+	}
+	if ct := values["datacontenttype"]; ct != "" && !isJSONMediaType(ct) {
+		return IngestRequest{}, fmt.Errorf("%w: datacontenttype %q is not JSON", ErrInvalidCloudEvent, ct)
+	}
+
+	req.EventID = values["id"]
+	req.Type = values["type"]
+	req.Source.Extra = map[string]string{ExtraCloudEventSource: values["source"]}
+	for name, key := range map[string]string{
+		"subject":    ExtraCloudEventSubject,
+		"time":       ExtraCloudEventTime,
+		"dataschema": ExtraCloudEventDataSchema,
+	} {
+		if v := values[name]; v != "" {
+			req.Source.Extra[key] = v
+		}
+	}
+
+	// Extensions become metadata so routes can filter on them.
+	for name, v := range attrs {
+		if cloudEventCore[name] {
+			continue
+		}
+		if !extensionNameRe.MatchString(name) {
+			return IngestRequest{}, fmt.Errorf("%w: extension name %q must be 1-20 lowercase letters or digits", ErrInvalidCloudEvent, name)
+		}
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]any)
+		}
+		req.Metadata[name] = v
+	}
+
+	if data = bytes.TrimSpace(data); len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, &req.Data); err != nil {
+			return IngestRequest{}, fmt.Errorf("%w: data must be a JSON object", ErrInvalidCloudEvent)
+		}
+	}
+	return req, nil
+}
+
+func isJSONMediaType(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
+
+// CloudEvent renders env as a structured-mode CloudEvent. Events ingested
+// as CloudEvents keep their original context attributes; other events get a
+// source of /tenants/<tenant_id> and their ingest time. The id is the
+// producer's event ID when one was supplied. Metadata entries
+// with valid extension names and scalar values become extensions.
+func (env EventEnvelope) CloudEvent() map[string]any {
+	ce := map[string]any{
+		"specversion": CloudEventsSpecVersion,
+		"id":          env.ID,
+		"type":        env.Type,
+		"source":      "/tenants/" + env.TenantID,
+		"time":        env.Status.IngestedAt.Format(time.RFC3339Nano),
+	}
+	if env.ClientEventID != "" {
+		ce["id"] = env.ClientEventID
+	}
+	for key, name := range map[string]string{
+		ExtraCloudEventSource:     "source",
+		ExtraCloudEventSubject:    "subject",
+		ExtraCloudEventTime:       "time",
+		ExtraCloudEventDataSchema: "dataschema",
+	} {
+		if v := env.Source.Extra[key]; v != "" {
+			ce[name] = v
+		}
+	}
+
+	for name, v := range env.Metadata {
+		if cloudEventCore[name] || !extensionNameRe.MatchString(name) {
+			continue
+		}
+		switch v.(type) {
+		case string, bool, float64:
+			ce[name] = v
+		}
+	}
+
+	if env.Data != nil {
+		ce["datacontenttype"] = "application/json"
+		ce["data"] = env.Data
+	}
+	return ce
+}
@@ -1,6 +1,19 @@
 package events
 
-import "time"
+import (
+	"time"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/schema"
+)
+
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryRetrying  = "RETRYING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+	// DeliveryDropped marks an event that routing sent nowhere.
+	DeliveryDropped = "DROPPED"
+)
 
 type SourceInfo struct {
 	Protocol  string            `json:"protocol"`
@@ -13,12 +26,25 @@ type SourceInfo struct {
 type EventStatus struct {
 	IngestedAt    time.Time `json:"ingested_at"`
 	DeliveryState string    `json:"delivery_state"`
+
+	// Validation is set when the event's type had an active schema at
+	// ingest time.
+	Validation *Validation `json:"validation,omitempty"`
+}
+
+type Validation struct {
+	SchemaVersion int                 `json:"schema_version"`
+	Valid         bool                `json:"valid"`
+	Errors        []schema.FieldError `json:"errors,omitempty"`
 }
 
 type EventEnvelope struct {
 	ID       string `json:"id"`
 	TenantID string `json:"tenant_id"`
 	Type     string `json:"type"`
+	// ClientEventID is the producer's own ID for the event, unique within
+	// the tenant.
+	ClientEventID string `json:"client_event_id,omitempty"`
 
 	Source   SourceInfo     `json:"source"`
 	Data     map[string]any `json:"data"`
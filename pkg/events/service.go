@@ -3,14 +3,24 @@ package events
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 )
 
+const maxEventIDLength = 128
+
 var (
-	ErrMissingType = errors.New("event type is required")
+	ErrMissingType    = errors.New("event type is required")
+	ErrInvalidType    = errors.New("event type must not contain control characters")
+	ErrInvalidEventID = errors.New("event id must be at most 128 characters")
+
+	// ErrDuplicate is returned together with the originally ingested
+	// envelope when a request repeats an idempotency key or event ID.
+	ErrDuplicate = errors.New("duplicate event")
 )
 
 type IngestRequest struct {
@@ -18,10 +28,37 @@ type IngestRequest struct {
 	Data     map[string]any
 	Metadata map[string]any
 	Source   SourceInfo
+
+	// EventID optionally supplies the producer's ID for the event, which
+	// deduplicates it within the tenant and, for CloudEvents, within the
+	// event's source, for the idempotency window like IdempotencyKey. The
+	// event still gets its own ID.
+	EventID        string
+	IdempotencyKey string
+
+	// Validation records the schema check done by the caller, if any.
+	Validation *Validation
+}
+
+func (r IngestRequest) validate() error {
+	if r.Type == "" {
+		return ErrMissingType
+	}
+	// Event types are echoed into line-based formats such as SSE.
+	if strings.IndexFunc(r.Type, unicode.IsControl) >= 0 {
+		return ErrInvalidType
+	}
+	if len(r.EventID) > maxEventIDLength {
+		return ErrInvalidEventID
+	}
+	return nil
 }
 
 type Service interface {
 	Ingest(ctx context.Context, tenantID string, req IngestRequest) (EventEnvelope, error)
+	// SetDeliveryState records the final state of an event that has no
+	// webhook deliveries to track it.
+	SetDeliveryState(ctx context.Context, eventID, state string) error
 }
 
 type logService struct {
@@ -33,20 +70,22 @@ func NewLogService(log logger.Logger) Service {
 }
 
 func (s *logService) Ingest(ctx context.Context, tenantID string, req IngestRequest) (EventEnvelope, error) {
-	if req.Type == "" {
-		return EventEnvelope{}, ErrMissingType
+	if err := req.validate(); err != nil {
+		return EventEnvelope{}, err
 	}
 
 	env := EventEnvelope{
-		ID:       uuid.NewString(),
-		TenantID: tenantID,
-		Type:     req.Type,
-		Source:   req.Source,
-		Data:     req.Data,
-		Metadata: req.Metadata,
+		ID:            uuid.NewString(),
+		TenantID:      tenantID,
+		Type:          req.Type,
+		ClientEventID: req.EventID,
+		Source:        req.Source,
+		Data:          req.Data,
+		Metadata:      req.Metadata,
 		Status: EventStatus{
 			IngestedAt:    time.Now().UTC(),
-			DeliveryState: "PENDING",
+			DeliveryState: DeliveryPending,
+			Validation:    req.Validation,
 		},
 	}
 
@@ -59,3 +98,8 @@ func (s *logService) Ingest(ctx context.Context, tenantID string, req IngestRequ
 
 	return env, nil
 }
+
+func (s *logService) SetDeliveryState(ctx context.Context, eventID, state string) error {
+	s.log.Info("event_delivery_state", "event_id", eventID, "state", state)
+	return nil
+}
@@ -0,0 +1,255 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+)
+
+type sqlService struct {
+	db                *sql.DB
+	log               logger.Logger
+	idempotencyWindow time.Duration
+}
+
+// NewSQLService stores every ingested envelope in the events table. Repeated
+// idempotency keys or event IDs within idempotencyWindow return the original
+// envelope with ErrDuplicate instead of creating a new event.
+func NewSQLService(db *sql.DB, log logger.Logger, idempotencyWindow time.Duration) Service {
+	return &sqlService{db: db, log: log, idempotencyWindow: idempotencyWindow}
+}
+
+func (s *sqlService) Ingest(ctx context.Context, tenantID string, req IngestRequest) (EventEnvelope, error) {
+	if err := req.validate(); err != nil {
+		return EventEnvelope{}, err
+	}
+
+	now := time.Now().UTC()
+	cutoff := now.Add(-s.idempotencyWindow)
+
+	env := EventEnvelope{
+		ID:            uuid.NewString(),
+		TenantID:      tenantID,
+		Type:          req.Type,
+		ClientEventID: req.EventID,
+		Source:        req.Source,
+		Data:          req.Data,
+		Metadata:      req.Metadata,
+		Status: EventStatus{
+			IngestedAt:    now,
+			DeliveryState: DeliveryPending,
+			Validation:    req.Validation,
+		},
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return EventEnvelope{}, fmt.Errorf("ingest event: %w", err)
+	}
+	defer tx.Rollback()
+
+	if req.IdempotencyKey != "" {
+		// Claim the key unless a live claim exists; an expired claim is
+		// taken over by this event.
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (tenant_id, key, event_id, created_at)
+             VALUES (?, ?, ?, ?)
+             ON CONFLICT(tenant_id, key) DO UPDATE
+                SET event_id = excluded.event_id, created_at = excluded.created_at
+              WHERE idempotency_keys.created_at <= ?`,
+			tenantID, req.IdempotencyKey, env.ID, now, cutoff,
+		)
+		if err != nil {
+			return EventEnvelope{}, fmt.Errorf("claim idempotency key: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return EventEnvelope{}, fmt.Errorf("claim idempotency key: %w", err)
+		} else if n == 0 {
+			var originalID string
+			if err := tx.QueryRowContext(ctx,
+				`SELECT event_id FROM idempotency_keys WHERE tenant_id = ? AND key = ?`,
+				tenantID, req.IdempotencyKey,
+			).Scan(&originalID); err != nil {
+				return EventEnvelope{}, fmt.Errorf("lookup idempotency key: %w", err)
+			}
+			return s.duplicate(ctx, tx, tenantID, originalID)
+		}
+	}
+
+	if req.EventID != "" {
+		// Producer event IDs are claimed the same way, so one can be reused
+		// once the window has passed.
+		source := clientEventSource(env)
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO client_event_ids (tenant_id, source, client_event_id, event_id, created_at)
+             VALUES (?, ?, ?, ?, ?)
+             ON CONFLICT(tenant_id, source, client_event_id) DO UPDATE
+                SET event_id = excluded.event_id, created_at = excluded.created_at
+              WHERE client_event_ids.created_at <= ?`,
+			tenantID, source, req.EventID, env.ID, now, cutoff,
+		)
+		if err != nil {
+			return EventEnvelope{}, fmt.Errorf("claim client event id: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return EventEnvelope{}, fmt.Errorf("claim client event id: %w", err)
+		} else if n == 0 {
+			var originalID string
+			if err := tx.QueryRowContext(ctx,
+				`SELECT event_id FROM client_event_ids
+                  WHERE tenant_id = ? AND source = ? AND client_event_id = ?`,
+				tenantID, source, req.EventID,
+			).Scan(&originalID); err != nil {
+				return EventEnvelope{}, fmt.Errorf("lookup client event id: %w", err)
+			}
+			return s.duplicate(ctx, tx, tenantID, originalID)
+		}
+	}
+
+	if err := insert(ctx, tx, env); err != nil {
+		return EventEnvelope{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return EventEnvelope{}, fmt.Errorf("ingest event: %w", err)
+	}
+
+	s.log.Info("event_ingested",
+		"event_id", env.ID,
+		"tenant_id", env.TenantID,
+		"type", env.Type,
+		"protocol", env.Source.Protocol,
+	)
+
+	return env, nil
+}
+
+func (s *sqlService) SetDeliveryState(ctx context.Context, eventID, state string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE events SET delivery_state = ? WHERE id = ?`,
+		state, eventID,
+	)
+	if err != nil {
+		return fmt.Errorf("set event delivery state: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlService) duplicate(ctx context.Context, q queryer, tenantID, eventID string) (EventEnvelope, error) {
+	original, err := get(ctx, q, eventID)
+	if err != nil {
+		return EventEnvelope{}, err
+	}
+	if original == nil || original.TenantID != tenantID {
+		return EventEnvelope{}, fmt.Errorf("idempotency claim references missing event %s", eventID)
+	}
+
+	s.log.Info("event_ingest_replayed",
+		"event_id", original.ID,
+		"tenant_id", tenantID,
+	)
+	return *original, ErrDuplicate
+}
+
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insert(ctx context.Context, q queryer, env EventEnvelope) error {
+	source, err := json.Marshal(env.Source)
+	if err != nil {
+		return fmt.Errorf("encode event source: %w", err)
+	}
+	data, err := json.Marshal(env.Data)
+	if err != nil {
+		return fmt.Errorf("encode event data: %w", err)
+	}
+	metadata, err := json.Marshal(env.Metadata)
+	if err != nil {
+		return fmt.Errorf("encode event metadata: %w", err)
+	}
+	var validation sql.NullString
+	if env.Status.Validation != nil {
+		b, err := json.Marshal(env.Status.Validation)
+		if err != nil {
+			return fmt.Errorf("encode event validation: %w", err)
+		}
+		validation = sql.NullString{String: string(b), Valid: true}
+	}
+
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO events (id, tenant_id, type, client_event_id, client_event_source, source, data, metadata, ingested_at, delivery_state, validation)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		env.ID, env.TenantID, env.Type, sql.NullString{String: env.ClientEventID, Valid: env.ClientEventID != ""},
+		clientEventSource(env), string(source), string(data), string(metadata),
+		env.Status.IngestedAt, env.Status.DeliveryState, validation,
+	)
+	if err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+	return nil
+}
+
+// Get returns the stored event id of tenantID, or nil if there is none.
+func Get(ctx context.Context, db *sql.DB, tenantID, id string) (*EventEnvelope, error) {
+	env, err := get(ctx, db, id)
+	if err != nil || env == nil || env.TenantID != tenantID {
+		return nil, err
+	}
+	return env, nil
+}
+
+const eventColumns = `id, tenant_id, type, client_event_id, source, data, metadata, ingested_at, delivery_state, validation`
+
+func get(ctx context.Context, q queryer, id string) (*EventEnvelope, error) {
+	return scanEvent(q.QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE id = ?`, id,
+	))
+}
+
+// clientEventSource scopes a client event ID: the CloudEvents source for
+// CloudEvents, empty for native events.
+func clientEventSource(env EventEnvelope) string {
+	return env.Source.Extra[ExtraCloudEventSource]
+}
+
+func scanEvent(row *sql.Row) (*EventEnvelope, error) {
+	var (
+		env                                               EventEnvelope
+		clientEventID, source, data, metadata, validation sql.NullString
+	)
+	err := row.Scan(&env.ID, &env.TenantID, &env.Type, &clientEventID, &source, &data, &metadata,
+		&env.Status.IngestedAt, &env.Status.DeliveryState, &validation)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get event: %w", err)
+	}
+	env.ClientEventID = clientEventID.String
+
+	if err := json.Unmarshal([]byte(source.String), &env.Source); err != nil {
+		return nil, fmt.Errorf("decode event source: %w", err)
+	}
+	if data.Valid {
+		if err := json.Unmarshal([]byte(data.String), &env.Data); err != nil {
+			return nil, fmt.Errorf("decode event data: %w", err)
+		}
+	}
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &env.Metadata); err != nil {
+			return nil, fmt.Errorf("decode event metadata: %w", err)
+		}
+	}
+	if validation.Valid {
+		if err := json.Unmarshal([]byte(validation.String), &env.Status.Validation); err != nil {
+			return nil, fmt.Errorf("decode event validation: %w", err)
+		}
+	}
+	return &env, nil
+}
@@ -0,0 +1,52 @@
+package realtime
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrChannelForbidden = errors.New("channel access denied")
+
+// Principal is the identity a realtime subscriber connected with.
+type Principal struct {
+	TenantID string
+	KeyID    string
+
+	// AllowedChannels optionally narrows the tenant namespace. Entries are
+	// full channel names; a trailing "*" matches any suffix.
+	AllowedChannels []string
+}
+
+type ChannelAuthorizer interface {
+	AuthorizeSubscribe(p Principal, channel string) error
+}
+
+type namespaceAuthorizer struct{}
+
+func NewChannelAuthorizer() ChannelAuthorizer {
+	return namespaceAuthorizer{}
+}
+
+func (namespaceAuthorizer) AuthorizeSubscribe(p Principal, channel string) error {
+	prefix := "tenant:" + p.TenantID + ":"
+	if p.TenantID == "" || !strings.HasPrefix(channel, prefix) || len(channel) == len(prefix) {
+		return ErrChannelForbidden
+	}
+
+	if len(p.AllowedChannels) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedChannels {
+		if matchChannel(allowed, channel) {
+			return nil
+		}
+	}
+	return ErrChannelForbidden
+}
+
+func matchChannel(pattern, channel string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(channel, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == channel
+}
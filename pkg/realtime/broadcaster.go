@@ -3,6 +3,8 @@ package realtime
 import (
 	"context"
 	"encoding/json"
+	"sync"
+	"time"
 
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
@@ -16,19 +18,67 @@ type rtBroadcaster struct {
 	log   logger.Logger
 	wsHub *WSHub
 	sse   *SSEBroker
+	seq   Sequencer
+	hist  History
+
+	// locks serializes BroadcastEvent per channel from sequence allocation
+	// through publish, so subscribers see sequences in order. Subscribers
+	// drop anything at or below the last sequence they saw, so publishing
+	// N+1 before N would lose N.
+	mu    sync.Mutex
+	locks map[string]*channelLock
+}
+
+type channelLock struct {
+	sync.Mutex
+	refs int
 }
 
-func NewBroadcaster(log logger.Logger, wsHub *WSHub, sse *SSEBroker) Broadcaster {
+func NewBroadcaster(log logger.Logger, wsHub *WSHub, sse *SSEBroker, seq Sequencer, hist History) Broadcaster {
 	return &rtBroadcaster{
 		log:   log,
 		wsHub: wsHub,
 		sse:   sse,
+		seq:   seq,
+		hist:  hist,
+		locks: make(map[string]*channelLock),
+	}
+}
+
+func (b *rtBroadcaster) lock(channel string) func() {
+	b.mu.Lock()
+	l, ok := b.locks[channel]
+	if !ok {
+		l = &channelLock{}
+		b.locks[channel] = l
+	}
+	l.refs++
+	b.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		b.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(b.locks, channel)
+		}
+		b.mu.Unlock()
 	}
 }
 
 func (b *rtBroadcaster) BroadcastEvent(ctx context.Context, channel string, env events.EventEnvelope) error {
+	unlock := b.lock(channel)
+	defer unlock()
+
+	seq, err := b.seq.Next(ctx, channel)
+	if err != nil {
+		b.log.Error("failed to assign channel sequence", "err", err, "channel", channel)
+		return err
+	}
+
 	payload, err := json.Marshal(map[string]any{
 		"channel": channel,
+		"seq":     seq,
 		"event":   env,
 	})
 	if err != nil {
@@ -36,8 +86,20 @@ func (b *rtBroadcaster) BroadcastEvent(ctx context.Context, channel string, env
 		return err
 	}
 
-	b.wsHub.Publish(channel, payload)
-	b.sse.Publish(channel, payload)
+	msg := Message{
+		Channel:   channel,
+		Seq:       seq,
+		EventID:   env.ID,
+		EventType: env.Type,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := b.hist.Append(ctx, msg); err != nil {
+		b.log.Warn("failed to record channel history", "err", err, "channel", channel, "seq", seq)
+	}
+
+	b.wsHub.Publish(msg)
+	b.sse.Publish(msg)
 
 	return nil
 }
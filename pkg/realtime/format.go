@@ -0,0 +1,52 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+)
+
+// Payload formats a subscriber can choose with ?format= on /ws and
+// /sse/stream.
+const (
+	// FormatNexus is the {"channel","seq","event"} wrapper around the
+	// envelope; it is the default.
+	FormatNexus = "nexus"
+	// FormatCloudEvents delivers each event as a structured CloudEvent
+	// with the channel and sequence in the nexuschannel and nexusseq
+	// extensions.
+	FormatCloudEvents = "cloudevents"
+)
+
+// ParseFormat maps a ?format= value onto a payload format; empty selects
+// FormatNexus.
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case "", FormatNexus:
+		return FormatNexus, nil
+	case FormatCloudEvents:
+		return FormatCloudEvents, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected %s or %s", s, FormatNexus, FormatCloudEvents)
+}
+
+// Encode returns the message payload in the given format. Payloads are
+// stored in the nexus format, so other formats are derived on delivery and
+// apply to replayed history as well.
+func (m Message) Encode(format string) ([]byte, error) {
+	if format != FormatCloudEvents {
+		return m.Payload, nil
+	}
+
+	var wrapped struct {
+		Event events.EventEnvelope `json:"event"`
+	}
+	if err := json.Unmarshal(m.Payload, &wrapped); err != nil {
+		return nil, fmt.Errorf("decode channel message: %w", err)
+	}
+	ce := wrapped.Event.CloudEvent()
+	ce["nexuschannel"] = m.Channel
+	ce["nexusseq"] = m.Seq
+	return json.Marshal(ce)
+}
@@ -0,0 +1,109 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type Message struct {
+	Channel   string
+	Seq       int64
+	EventID   string
+	EventType string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+type History interface {
+	Append(ctx context.Context, msg Message) error
+	Since(ctx context.Context, channel string, afterSeq int64, limit int) ([]Message, error)
+	SeqForEvent(ctx context.Context, channel, eventID string) (int64, bool, error)
+	SeqBefore(ctx context.Context, channel string, t time.Time) (int64, error)
+}
+
+type sqlHistory struct {
+	db        *sql.DB
+	retention int64
+}
+
+// NewSQLHistory keeps the last retention messages of every channel so that
+// reconnecting subscribers can catch up on what they missed.
+func NewSQLHistory(db *sql.DB, retention int) History {
+	return &sqlHistory{db: db, retention: int64(retention)}
+}
+
+func (h *sqlHistory) Append(ctx context.Context, msg Message) error {
+	_, err := h.db.ExecContext(ctx,
+		`INSERT INTO channel_messages (channel, seq, event_id, event_type, payload, created_at)
+         VALUES (?, ?, ?, ?, ?, ?)`,
+		msg.Channel, msg.Seq, msg.EventID, msg.EventType, msg.Payload, msg.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("append channel message: %w", err)
+	}
+
+	if msg.Seq > h.retention {
+		_, err = h.db.ExecContext(ctx,
+			`DELETE FROM channel_messages WHERE channel = ? AND seq <= ?`,
+			msg.Channel, msg.Seq-h.retention,
+		)
+		if err != nil {
+			return fmt.Errorf("prune channel messages: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *sqlHistory) Since(ctx context.Context, channel string, afterSeq int64, limit int) ([]Message, error) {
+	rows, err := h.db.QueryContext(ctx,
+		`SELECT channel, seq, event_id, event_type, payload, created_at
+           FROM channel_messages
+          WHERE channel = ? AND seq > ?
+          ORDER BY seq ASC
+          LIMIT ?`,
+		channel, afterSeq, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list channel messages: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.Channel, &m.Seq, &m.EventID, &m.EventType, &m.Payload, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan channel message: %w", err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (h *sqlHistory) SeqForEvent(ctx context.Context, channel, eventID string) (int64, bool, error) {
+	var seq int64
+	err := h.db.QueryRowContext(ctx,
+		`SELECT seq FROM channel_messages WHERE channel = ? AND event_id = ?`,
+		channel, eventID,
+	).Scan(&seq)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("find channel message by event: %w", err)
+	}
+	return seq, true, nil
+}
+
+func (h *sqlHistory) SeqBefore(ctx context.Context, channel string, t time.Time) (int64, error) {
+	var seq sql.NullInt64
+	err := h.db.QueryRowContext(ctx,
+		`SELECT MAX(seq) FROM channel_messages WHERE channel = ? AND created_at < ?`,
+		channel, t.UTC(),
+	).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("find channel message by time: %w", err)
+	}
+	return seq.Int64, nil
+}
@@ -0,0 +1,33 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type Sequencer interface {
+	Next(ctx context.Context, channel string) (int64, error)
+}
+
+type sqlSequencer struct {
+	db *sql.DB
+}
+
+func NewSQLSequencer(db *sql.DB) Sequencer {
+	return &sqlSequencer{db: db}
+}
+
+func (s *sqlSequencer) Next(ctx context.Context, channel string) (int64, error) {
+	var seq int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO channel_sequences (channel, seq) VALUES (?, 1)
+         ON CONFLICT(channel) DO UPDATE SET seq = seq + 1
+         RETURNING seq`,
+		channel,
+	).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("next channel sequence: %w", err)
+	}
+	return seq, nil
+}
@@ -2,7 +2,7 @@ package realtime
 
 import "sync"
 
-type SSEClient chan []byte
+type SSEClient chan Message
 
 type SSEBroker struct {
 	mu       sync.RWMutex
@@ -41,11 +41,11 @@ func (b *SSEBroker) Unsubscribe(channel string, client SSEClient) {
 	}
 }
 
-func (b *SSEBroker) Publish(channel string, msg []byte) {
+func (b *SSEBroker) Publish(msg Message) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	if subs, ok := b.channels[channel]; ok {
+	if subs, ok := b.channels[msg.Channel]; ok {
 		for client := range subs {
 			select {
 			case client <- msg:
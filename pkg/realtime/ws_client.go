@@ -1,37 +1,100 @@
 package realtime
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 )
 
+const (
+	wsReplayLimit   = 1000
+	wsReplayTimeout = 5 * time.Second
+	wsMaxPending    = 256
+
+	// wsWriteWait bounds a single write so a peer that stops reading ends
+	// the write pump instead of stalling it.
+	wsWriteWait = 10 * time.Second
+)
+
+var errInvalidCursor = errors.New("invalid replay cursor")
+
 type WSClient struct {
-	Conn   *websocket.Conn
-	Send   chan []byte
-	Log    logger.Logger
-	Hub    *WSHub
-	Tenant string
+	Conn    *websocket.Conn
+	Send    chan []byte
+	Log     logger.Logger
+	Hub     *WSHub
+	History History
+	Auth    ChannelAuthorizer
+	Tenant  string
+
+	principal Principal
+	format    string
+
+	mu   sync.Mutex
+	subs map[string]*wsSubscription
+	done chan struct{}
+}
+
+// wsSubscription tracks delivery on one channel. While a replay is in
+// progress live messages are parked in pending so they cannot overtake the
+// replayed history; overflowed records that some did not fit.
+type wsSubscription struct {
+	replaying  bool
+	pending    []Message
+	overflowed bool
+	lastSeq    int64
 }
 
 type WSSubscribeMessage struct {
 	Action  string `json:"action"`
 	Channel string `json:"channel"`
+
+	// Optional replay cursor for "subscribe"; at most one should be set.
+	SinceSeq     *int64 `json:"since_seq,omitempty"`
+	SinceEventID string `json:"since_event_id,omitempty"`
+	SinceTime    string `json:"since_time,omitempty"`
+}
+
+type wsControlMessage struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel,omitempty"`
+	Error     string `json:"error,omitempty"`
+	OldestSeq int64  `json:"oldest_seq,omitempty"`
+	LastSeq   int64  `json:"last_seq,omitempty"`
 }
 
-func NewWSClient(conn *websocket.Conn, log logger.Logger, hub *WSHub, tenant string) *WSClient {
+func NewWSClient(
+	conn *websocket.Conn,
+	log logger.Logger,
+	hub *WSHub,
+	history History,
+	auth ChannelAuthorizer,
+	principal Principal,
+	format string,
+) *WSClient {
 	return &WSClient{
-		Conn:   conn,
-		Send:   make(chan []byte, 32),
-		Log:    log,
-		Hub:    hub,
-		Tenant: tenant,
+		Conn:      conn,
+		Send:      make(chan []byte, 32),
+		Log:       log,
+		Hub:       hub,
+		History:   history,
+		Auth:      auth,
+		Tenant:    principal.TenantID,
+		principal: principal,
+		format:    format,
+		subs:      make(map[string]*wsSubscription),
+		done:      make(chan struct{}),
 	}
 }
 
 func (c *WSClient) ReadPump() {
 	defer func() {
+		c.unsubscribeAll()
 		c.Conn.Close()
 	}()
 
@@ -50,11 +113,13 @@ func (c *WSClient) ReadPump() {
 
 		switch m.Action {
 		case "subscribe":
-			c.Hub.Register(m.Channel, c)
-			c.Log.Info("ws subscribed", "tenant", c.Tenant, "channel", m.Channel)
+			c.subscribe(m)
 
 		case "unsubscribe":
 			c.Hub.Unregister(m.Channel, c)
+			c.mu.Lock()
+			delete(c.subs, m.Channel)
+			c.mu.Unlock()
 			c.Log.Info("ws unsubscribed", "tenant", c.Tenant, "channel", m.Channel)
 
 		default:
@@ -65,13 +130,239 @@ func (c *WSClient) ReadPump() {
 
 func (c *WSClient) WritePump() {
 	defer func() {
+		close(c.done)
 		c.Conn.Close()
 	}()
 
 	for msg := range c.Send {
+		c.Conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
 		if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
 			c.Log.Warn("ws write error", "err", err)
 			return
 		}
 	}
 }
+
+func (c *WSClient) subscribe(m WSSubscribeMessage) {
+	if err := c.Auth.AuthorizeSubscribe(c.principal, m.Channel); err != nil {
+		c.Log.Warn("ws subscribe denied", "tenant", c.Tenant, "channel", m.Channel)
+		c.sendControl(wsControlMessage{Type: "error", Channel: m.Channel, Error: err.Error()})
+		return
+	}
+
+	replay := m.SinceSeq != nil || m.SinceEventID != "" || m.SinceTime != ""
+
+	var afterSeq int64
+	var truncated bool
+	if replay {
+		ctx, cancel := context.WithTimeout(context.Background(), wsReplayTimeout)
+		var err error
+		afterSeq, truncated, err = c.resolveCursor(ctx, m)
+		cancel()
+		if err != nil {
+			c.Log.Warn("invalid ws replay cursor", "err", err, "channel", m.Channel)
+			c.sendControl(wsControlMessage{Type: "error", Channel: m.Channel, Error: err.Error()})
+			return
+		}
+	}
+
+	sub := &wsSubscription{replaying: replay}
+	c.mu.Lock()
+	c.subs[m.Channel] = sub
+	c.mu.Unlock()
+
+	c.Hub.Register(m.Channel, c)
+	c.Log.Info("ws subscribed", "tenant", c.Tenant, "channel", m.Channel, "replay", replay)
+
+	if !replay {
+		return
+	}
+	c.replay(m.Channel, sub, afterSeq, truncated)
+}
+
+// replay sends history after afterSeq page by page until it has caught up
+// with the live messages parked in sub.pending, then hands over to live
+// delivery. If pending overflowed meanwhile, history is paged again rather
+// than dropping what did not fit. A cursor missing from history (truncated)
+// or any sequence missing from it is reported with replay_truncated.
+func (c *WSClient) replay(channel string, sub *wsSubscription, afterSeq int64, truncated bool) {
+	lastSeq := afterSeq
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), wsReplayTimeout)
+		missed, err := c.History.Since(ctx, channel, lastSeq, wsReplayLimit)
+		cancel()
+		if err != nil {
+			// Going live now would leave a silent gap; make the client
+			// subscribe again instead.
+			c.Log.Error("ws replay failed", "err", err, "channel", channel)
+			c.Hub.Unregister(channel, c)
+			c.mu.Lock()
+			delete(c.subs, channel)
+			c.mu.Unlock()
+			c.sendControl(wsControlMessage{Type: "error", Channel: channel, Error: "replay failed, subscribe again"})
+			return
+		}
+		if len(missed) > 0 && missed[0].Seq > lastSeq+1 {
+			c.sendControl(wsControlMessage{Type: "replay_truncated", Channel: channel, OldestSeq: missed[0].Seq})
+		} else if truncated {
+			c.sendControl(wsControlMessage{Type: "replay_truncated", Channel: channel})
+		}
+		truncated = false
+
+		for _, msg := range missed {
+			if payload, ok := c.encode(msg); ok && !c.sendBlocking(payload) {
+				return
+			}
+			lastSeq = msg.Seq
+		}
+		if len(missed) == wsReplayLimit {
+			continue
+		}
+
+		c.mu.Lock()
+		if sub.overflowed {
+			sub.pending, sub.overflowed = nil, false
+			c.mu.Unlock()
+			continue
+		}
+		// Queued under c.mu so it is ordered ahead of the pending messages,
+		// but never waited for: Publish reaches deliver through c.mu, so a
+		// client whose buffer is full is disconnected instead.
+		if payload, ok := c.control(wsControlMessage{Type: "replay_complete", Channel: channel, LastSeq: lastSeq}); ok {
+			select {
+			case c.Send <- payload:
+			default:
+				c.mu.Unlock()
+				c.Log.Warn("closing slow ws client", "tenant", c.Tenant, "channel", channel)
+				c.Conn.Close()
+				return
+			}
+		}
+		sub.replaying = false
+		if lastSeq > sub.lastSeq {
+			sub.lastSeq = lastSeq
+		}
+		for _, msg := range sub.pending {
+			c.deliverLocked(sub, msg)
+		}
+		sub.pending = nil
+		c.mu.Unlock()
+		return
+	}
+}
+
+// resolveCursor maps the subscribe cursor onto the channel sequence after
+// which replay starts. truncated reports that the cursor could not be found
+// in retained history.
+func (c *WSClient) resolveCursor(ctx context.Context, m WSSubscribeMessage) (int64, bool, error) {
+	switch {
+	case m.SinceSeq != nil:
+		if *m.SinceSeq < 0 {
+			return 0, false, errInvalidCursor
+		}
+		return *m.SinceSeq, false, nil
+
+	case m.SinceEventID != "":
+		seq, ok, err := c.History.SeqForEvent(ctx, m.Channel, m.SinceEventID)
+		if err != nil {
+			return 0, false, err
+		}
+		return seq, !ok, nil
+
+	default:
+		t, err := time.Parse(time.RFC3339Nano, m.SinceTime)
+		if err != nil {
+			return 0, false, errInvalidCursor
+		}
+		seq, err := c.History.SeqBefore(ctx, m.Channel, t)
+		if err != nil {
+			return 0, false, err
+		}
+		return seq, false, nil
+	}
+}
+
+func (c *WSClient) deliver(msg Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub, ok := c.subs[msg.Channel]
+	if !ok {
+		return
+	}
+	if sub.replaying {
+		if len(sub.pending) < wsMaxPending {
+			sub.pending = append(sub.pending, msg)
+		} else {
+			sub.overflowed = true
+		}
+		return
+	}
+	c.deliverLocked(sub, msg)
+}
+
+func (c *WSClient) deliverLocked(sub *wsSubscription, msg Message) {
+	if msg.Seq <= sub.lastSeq {
+		return
+	}
+	payload, ok := c.encode(msg)
+	if !ok {
+		sub.lastSeq = msg.Seq
+		return
+	}
+	select {
+	case c.Send <- payload:
+		sub.lastSeq = msg.Seq
+	default:
+		// Slow consumer; drop. Subscribers detect the gap by sequence.
+	}
+}
+
+// encode renders msg in the client's format; a message that cannot be
+// encoded is logged and skipped.
+func (c *WSClient) encode(msg Message) ([]byte, bool) {
+	payload, err := msg.Encode(c.format)
+	if err != nil {
+		c.Log.Error("failed to encode ws message", "err", err, "channel", msg.Channel, "seq", msg.Seq)
+		return nil, false
+	}
+	return payload, true
+}
+
+func (c *WSClient) sendControl(m wsControlMessage) {
+	if payload, ok := c.control(m); ok {
+		c.sendBlocking(payload)
+	}
+}
+
+func (c *WSClient) control(m wsControlMessage) ([]byte, bool) {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		c.Log.Error("failed to marshal ws control message", "err", err)
+		return nil, false
+	}
+	return payload, true
+}
+
+func (c *WSClient) sendBlocking(payload []byte) bool {
+	select {
+	case c.Send <- payload:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+func (c *WSClient) unsubscribeAll() {
+	c.mu.Lock()
+	channels := make([]string, 0, len(c.subs))
+	for ch := range c.subs {
+		channels = append(channels, ch)
+	}
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		c.Hub.Unregister(ch, c)
+	}
+	close(c.Send)
+}
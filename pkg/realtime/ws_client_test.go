@@ -0,0 +1,123 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+)
+
+const testChannel = "tenant:t1:orders"
+
+type memHistory struct{ msgs []Message }
+
+func (h *memHistory) Append(ctx context.Context, msg Message) error {
+	h.msgs = append(h.msgs, msg)
+	return nil
+}
+
+func (h *memHistory) Since(ctx context.Context, channel string, afterSeq int64, limit int) ([]Message, error) {
+	var out []Message
+	for _, m := range h.msgs {
+		if m.Channel == channel && m.Seq > afterSeq && len(out) < limit {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (h *memHistory) SeqForEvent(ctx context.Context, channel, eventID string) (int64, bool, error) {
+	return 0, false, nil
+}
+
+func (h *memHistory) SeqBefore(ctx context.Context, channel string, t time.Time) (int64, error) {
+	return 0, nil
+}
+
+// dialStalled returns a server-side client whose peer never reads.
+func dialStalled(t *testing.T, hub *WSHub, history History) (*WSClient, *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	c := NewWSClient(<-conns, logger.New("error"), hub, history, NewChannelAuthorizer(),
+		Principal{TenantID: "t1"}, FormatNexus)
+	return c, peer
+}
+
+func within(t *testing.T, d time.Duration, what string, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(d):
+		t.Fatalf("%s blocked for more than %s", what, d)
+	}
+}
+
+func TestReplayDoesNotBlockPublishOnFullClient(t *testing.T) {
+	hub := NewWSHub()
+	c, peer := dialStalled(t, hub, &memHistory{})
+
+	// Nothing drains Send, as when the write pump is stuck on a peer that
+	// stopped reading.
+	for len(c.Send) < cap(c.Send) {
+		c.Send <- []byte("{}")
+	}
+
+	since := int64(0)
+	within(t, 2*time.Second, "subscribe", func() {
+		c.subscribe(WSSubscribeMessage{Action: "subscribe", Channel: testChannel, SinceSeq: &since})
+	})
+	within(t, 2*time.Second, "publish", func() {
+		hub.Publish(Message{Channel: testChannel, Seq: 1, EventID: "e1", EventType: "order.created", Payload: []byte(`{}`)})
+	})
+
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := peer.ReadMessage()
+	var ne net.Error
+	if err == nil || errors.As(err, &ne) && ne.Timeout() {
+		t.Fatalf("slow client was not disconnected: %v", err)
+	}
+}
+
+func TestPublishToNonReadingClient(t *testing.T) {
+	hub := NewWSHub()
+	c, _ := dialStalled(t, hub, &memHistory{})
+	go c.WritePump()
+	defer c.unsubscribeAll()
+
+	c.subscribe(WSSubscribeMessage{Action: "subscribe", Channel: testChannel})
+
+	payload := []byte(`{"blob":"` + strings.Repeat("x", 64<<10) + `"}`)
+	within(t, 2*time.Second, "publish", func() {
+		for i := int64(1); i <= 500; i++ {
+			hub.Publish(Message{Channel: testChannel, Seq: i, EventID: "e", EventType: "order.created", Payload: payload})
+		}
+	})
+}
@@ -35,17 +35,13 @@ func (h *WSHub) Unregister(channel string, c *WSClient) {
 	}
 }
 
-func (h *WSHub) Publish(channel string, msg []byte) {
+func (h *WSHub) Publish(msg Message) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	if subs, ok := h.channels[channel]; ok {
+	if subs, ok := h.channels[msg.Channel]; ok {
 		for client := range subs {
-			select {
-			case client.Send <- msg:
-			default:
-				// Slow consumer; drop message or close in future
-			}
+			client.deliver(msg)
 		}
 	}
 }
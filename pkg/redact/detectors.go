@@ -0,0 +1,101 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+type detector struct {
+	re *regexp.Regexp
+	// valid filters out pattern matches that are not really this kind of
+	// data; nil accepts every match.
+	valid func(string) bool
+	// mask hides a match while keeping enough of it to be recognisable.
+	mask func(string) string
+}
+
+// detectorOrder runs card before phone so a card number is never half-masked
+// as a phone number.
+var detectorOrder = []string{"card", "email", "phone"}
+
+var detectors = map[string]detector{
+	"email": {
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		mask: maskEmail,
+	},
+	"card": {
+		re:    regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid: luhnValid,
+		mask:  func(s string) string { return maskDigits(s, 4) },
+	},
+	"phone": {
+		re:   regexp.MustCompile(`\+?\(?\d{1,4}\)?[\s.\-]?\d(?:[\s.\-]?\d){6,13}\b`),
+		mask: func(s string) string { return maskDigits(s, 2) },
+	},
+}
+
+func (d detector) redact(s, mode, salt string) string {
+	return d.re.ReplaceAllStringFunc(s, func(match string) string {
+		if d.valid != nil && !d.valid(match) {
+			return match
+		}
+		if mode == ModeHash {
+			return hashString(salt, match)
+		}
+		return d.mask(match)
+	})
+}
+
+// maskEmail keeps the first character of the local part and the domain:
+// jane@example.com becomes j***@example.com.
+func maskEmail(s string) string {
+	local, domain, _ := strings.Cut(s, "@")
+	if local == "" {
+		return "***@" + domain
+	}
+	return local[:1] + "***@" + domain
+}
+
+// maskDigits replaces every digit but the last keep with '*', leaving
+// separators in place.
+func maskDigits(s string, keep int) string {
+	digits := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+
+	var b strings.Builder
+	seen := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			seen++
+			if seen <= digits-keep {
+				b.WriteByte('*')
+				continue
+			}
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,74 @@
+package redact
+
+import "testing"
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"4111111111111111", true},
+		{"4111 1111 1111 1111", true},
+		{"4111-1111-1111-1111", true},
+		{"5500005555555559", true},
+		{"378282246310005", true},
+		{"4111111111111112", false},
+		{"1234567890123456", false},
+		{"0000000000", false}, // too short, even though the checksum holds
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := luhnValid(tt.s); got != tt.want {
+			t.Errorf("luhnValid(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestDetectorMask(t *testing.T) {
+	tests := []struct {
+		kind, in, want string
+	}{
+		{"card", "card 4111111111111111 on file", "card ************1111 on file"},
+		{"card", "card 4111 1111 1111 1111", "card **** **** **** 1111"},
+		{"card", "order 4111111111111112", "order 4111111111111112"},
+		{"card", "call +1 415 555 0100", "call +1 415 555 0100"},
+		{"phone", "call +1 415 555 0100", "call +* *** *** **00"},
+		{"phone", "call 415-555-0100 now", "call ***-***-**00 now"},
+		{"phone", "room 42", "room 42"},
+		{"email", "mail jane.doe@example.com", "mail j***@example.com"},
+	}
+	for _, tt := range tests {
+		if got := detectors[tt.kind].redact(tt.in, ModeMask, ""); got != tt.want {
+			t.Errorf("%s redact(%q) = %q, want %q", tt.kind, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDetectorOrderKeepsCardsWhole(t *testing.T) {
+	s := "paid with 4111 1111 1111 1111, call +1 415 555 0100"
+	for _, kind := range detectorOrder {
+		s = detectors[kind].redact(s, ModeMask, "")
+	}
+	want := "paid with **** **** **** 1111, call +* *** *** **00"
+	if s != want {
+		t.Errorf("redacted = %q, want %q", s, want)
+	}
+}
+
+func TestMaskDigits(t *testing.T) {
+	tests := []struct {
+		s    string
+		keep int
+		want string
+	}{
+		{"12345", 2, "***45"},
+		{"12-34", 2, "**-34"},
+		{"12", 4, "12"},
+		{"abc", 2, "abc"},
+	}
+	for _, tt := range tests {
+		if got := maskDigits(tt.s, tt.keep); got != tt.want {
+			t.Errorf("maskDigits(%q, %d) = %q, want %q", tt.s, tt.keep, got, tt.want)
+		}
+	}
+}
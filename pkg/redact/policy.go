@@ -0,0 +1,208 @@
+// Package redact removes personal data from event payloads before they are
+// stored or delivered, following a per-tenant policy.
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ModeMask = "mask"
+	ModeHash = "hash"
+)
+
+// Masked replaces whole values selected by a path rule in mask mode.
+const Masked = "[REDACTED]"
+
+var ErrInvalidPolicy = errors.New("invalid redaction policy")
+
+// Policy lists what to redact from an event's data and metadata:
+//
+//	{"rules":     [{"path": "data.customer.email", "mode": "hash"},
+//	               {"path": "data.items.*.card", "mode": "mask"}],
+//	 "detectors": [{"type": "email", "mode": "mask"},
+//	               {"type": "card", "mode": "mask"}]}
+//
+// Rules replace the value at a dotted path rooted at "data" or "metadata";
+// a "*" segment matches every key of an object or element of an array.
+// Detectors scan every string value for patterns and replace just the
+// matching text. Rules run first, then detectors in a fixed order.
+type Policy struct {
+	Rules     []Rule     `json:"rules,omitempty"`
+	Detectors []Detector `json:"detectors,omitempty"`
+}
+
+type Rule struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+}
+
+type Detector struct {
+	Type string `json:"type"` // email, card or phone
+	Mode string `json:"mode"`
+}
+
+// Parse decodes and validates a stored policy; empty input yields nil.
+func Parse(raw []byte) (*Policy, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var p Policy
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	for _, r := range p.Rules {
+		root, rest, _ := strings.Cut(r.Path, ".")
+		if (root != "data" && root != "metadata") || rest == "" || strings.Contains(rest, "..") ||
+			strings.HasSuffix(rest, ".") {
+			return nil, fmt.Errorf("%w: path %q must be data.<field> or metadata.<field>", ErrInvalidPolicy, r.Path)
+		}
+		if err := validateMode(r.Mode); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range p.Detectors {
+		if _, ok := detectors[d.Type]; !ok {
+			return nil, fmt.Errorf("%w: unknown detector %q", ErrInvalidPolicy, d.Type)
+		}
+		if err := validateMode(d.Mode); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+func validateMode(mode string) error {
+	if mode != ModeMask && mode != ModeHash {
+		return fmt.Errorf("%w: mode must be %s or %s, got %q", ErrInvalidPolicy, ModeMask, ModeHash, mode)
+	}
+	return nil
+}
+
+// Apply returns redacted copies of data and metadata. Hash mode uses an
+// HMAC keyed by salt, so equal values still correlate within a tenant
+// without being reversible by hashing guesses.
+func (p *Policy) Apply(salt string, data, metadata map[string]any) (map[string]any, map[string]any) {
+	doc := map[string]any{"data": copyValue(data), "metadata": copyValue(metadata)}
+
+	for _, r := range p.Rules {
+		replacePath(doc, strings.Split(r.Path, "."), func(v any) any {
+			if r.Mode == ModeHash {
+				return hashValue(salt, v)
+			}
+			return Masked
+		})
+	}
+	if len(p.Detectors) > 0 {
+		modes := make(map[string]string, len(p.Detectors))
+		for _, d := range p.Detectors {
+			modes[d.Type] = d.Mode
+		}
+		doc = scanStrings(doc, func(s string) string {
+			for _, name := range detectorOrder {
+				if mode, ok := modes[name]; ok {
+					s = detectors[name].redact(s, mode, salt)
+				}
+			}
+			return s
+		}).(map[string]any)
+	}
+
+	outData, _ := doc["data"].(map[string]any)
+	outMeta, _ := doc["metadata"].(map[string]any)
+	return outData, outMeta
+}
+
+func replacePath(cur any, path []string, replace func(any) any) {
+	key, rest := path[0], path[1:]
+
+	switch node := cur.(type) {
+	case map[string]any:
+		for k, v := range node {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				node[k] = replace(v)
+			} else {
+				replacePath(v, rest, replace)
+			}
+		}
+	case []any:
+		if key != "*" {
+			return
+		}
+		for i, v := range node {
+			if len(rest) == 0 {
+				node[i] = replace(v)
+			} else {
+				replacePath(v, rest, replace)
+			}
+		}
+	}
+}
+
+func scanStrings(v any, fn func(string) string) any {
+	switch t := v.(type) {
+	case string:
+		return fn(t)
+	case map[string]any:
+		for k, val := range t {
+			t[k] = scanStrings(val, fn)
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = scanStrings(val, fn)
+		}
+	}
+	return v
+}
+
+func hashValue(salt string, v any) string {
+	s, ok := v.(string)
+	if !ok {
+		b, _ := json.Marshal(v)
+		s = string(b)
+	}
+	return hashString(salt, s)
+}
+
+func hashString(salt, s string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(s))
+	return "hash:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// copyValue deep-copies decoded JSON so redaction never touches the caller's
+// maps. A nil map stays nil.
+func copyValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		if t == nil {
+			return nil
+		}
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = copyValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = copyValue(val)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+)
+
+// Redactor applies each tenant's stored policy. Policies are cached and
+// reloaded once older than the refresh interval, which bounds how long a
+// policy change takes to reach a running gateway.
+type Redactor struct {
+	store   *control.Store
+	refresh time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]cachedPolicy
+}
+
+type cachedPolicy struct {
+	policy *Policy // nil when the tenant has none
+	salt   string
+	loaded time.Time
+}
+
+func NewRedactor(store *control.Store, refresh time.Duration) *Redactor {
+	return &Redactor{
+		store:   store,
+		refresh: refresh,
+		entries: make(map[string]cachedPolicy),
+	}
+}
+
+// Redact returns data and metadata with the tenant's policy applied, or
+// unchanged when the tenant has no policy.
+func (r *Redactor) Redact(ctx context.Context, tenantID string, data, metadata map[string]any) (map[string]any, map[string]any, error) {
+	entry, err := r.policy(ctx, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry.policy == nil {
+		return data, metadata, nil
+	}
+	data, metadata = entry.policy.Apply(entry.salt, data, metadata)
+	return data, metadata, nil
+}
+
+func (r *Redactor) policy(ctx context.Context, tenantID string) (cachedPolicy, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[tenantID]
+	r.mu.RUnlock()
+	if ok && time.Since(entry.loaded) < r.refresh {
+		return entry, nil
+	}
+
+	stored, err := r.store.GetRedactionPolicy(ctx, tenantID)
+	if err != nil {
+		return cachedPolicy{}, fmt.Errorf("load redaction policy: %w", err)
+	}
+	entry = cachedPolicy{loaded: time.Now()}
+	if stored != nil {
+		if entry.policy, err = Parse(stored.Policy); err != nil {
+			return cachedPolicy{}, fmt.Errorf("load redaction policy: %w", err)
+		}
+		entry.salt = stored.Salt
+	}
+
+	r.mu.Lock()
+	r.entries[tenantID] = entry
+	r.mu.Unlock()
+	return entry, nil
+}
@@ -3,27 +3,183 @@ package routing
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
 )
 
+// Engine resolves events to target channels from per-tenant routing tables.
+// Matching routes are evaluated by priority until one marked StopProcessing
+// matches; when none match, the tenant's fallback applies. Tables are
+// compiled on first use and cached in memory. Cached tables are dropped by
+// Invalidate, or by Refresh once the tenant's route version in the store
+// moves on, which is how changes made by another process are picked up.
 type Engine struct {
+	log   logger.Logger
 	store *control.Store
+
+	mu     sync.RWMutex
+	tables map[string]*table
+	epoch  uint64 // bumped on every invalidation
+}
+
+func NewEngine(log logger.Logger, store *control.Store) *Engine {
+	return &Engine{
+		log:    log,
+		store:  store,
+		tables: make(map[string]*table),
+	}
 }
 
-func NewEngine(store *control.Store) *Engine {
-	return &Engine{store: store}
+// Resolve returns the targets env should be delivered to.
+func (e *Engine) Resolve(ctx context.Context, env events.EventEnvelope) ([]Target, error) {
+	t, err := e.table(ctx, env.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve targets: %w", err)
+	}
+	return t.resolve(env).Targets, nil
 }
 
-func (e *Engine) ResolveChannels(ctx context.Context, tenantID, eventType string) ([]string, error) {
-	routes, err := e.store.FindRoutesForEvent(ctx, tenantID, eventType)
+// ResolveChannels is Resolve without the per-target transforms.
+func (e *Engine) ResolveChannels(ctx context.Context, env events.EventEnvelope) ([]string, error) {
+	t, err := e.table(ctx, env.TenantID)
 	if err != nil {
 		return nil, fmt.Errorf("resolve channels: %w", err)
 	}
+	return t.resolve(env).Channels(), nil
+}
+
+// DryRun resolves env against the tenant's stored routes, bypassing the
+// cache, and reports which routes matched. A proposed route, if given, is
+// evaluated as though it had just been saved.
+func (e *Engine) DryRun(ctx context.Context, env events.EventEnvelope, proposed *control.Route) (*Resolution, error) {
+	routes, err := e.store.ListRoutes(ctx, env.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("dry run: %w", err)
+	}
+	fallback, err := e.store.GetRoutingFallback(ctx, env.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("dry run: %w", err)
+	}
+	if fallback == nil {
+		fallback = &control.RoutingFallback{Mode: control.FallbackDefault}
+	}
+
+	if proposed != nil {
+		if _, err := control.CompileMatcher(proposed.MatchType, proposed.MatchValue); err != nil {
+			return nil, err
+		}
+		if _, err := ParseFilter(proposed.Filter); err != nil {
+			return nil, err
+		}
+		if _, err := ParseTransform(proposed.Transform); err != nil {
+			return nil, err
+		}
+		p := *proposed
+		p.MatchType = strings.ToUpper(p.MatchType)
+
+		// Newest among equal priorities, as if created now.
+		i := sort.Search(len(routes), func(i int) bool { return routes[i].Priority > p.Priority })
+		routes = append(routes[:i], append([]control.Route{p}, routes[i:]...)...)
+	}
+
+	t, err := compileTable(0, routes, *fallback)
+	if err != nil {
+		return nil, fmt.Errorf("dry run: %w", err)
+	}
+	res := t.resolve(env)
+	return &res, nil
+}
+
+// DefaultChannel is where a tenant's unrouted events go unless the tenant
+// configures another fallback.
+func DefaultChannel(tenantID string) string {
+	return fmt.Sprintf("tenant:%s:events", tenantID)
+}
+
+// Invalidate drops the cached table for a tenant so the next event reloads
+// it from the store.
+func (e *Engine) Invalidate(tenantID string) {
+	e.mu.Lock()
+	delete(e.tables, tenantID)
+	e.epoch++
+	e.mu.Unlock()
+}
+
+// Refresh drops every cached table whose tenant's route version has changed
+// since it was compiled.
+func (e *Engine) Refresh(ctx context.Context) error {
+	versions, err := e.store.RouteVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("refresh routes: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for tenantID, t := range e.tables {
+		if versions[tenantID] != t.version {
+			delete(e.tables, tenantID)
+			e.epoch++
+		}
+	}
+	return nil
+}
+
+// Run calls Refresh every interval until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Refresh(ctx); err != nil && ctx.Err() == nil {
+				e.log.Error("route refresh failed", "err", err)
+			}
+		}
+	}
+}
+
+func (e *Engine) table(ctx context.Context, tenantID string) (*table, error) {
+	e.mu.RLock()
+	t, epoch := e.tables[tenantID], e.epoch
+	e.mu.RUnlock()
+	if t != nil {
+		return t, nil
+	}
+
+	// Read the version first: a change landing mid-load leaves the table
+	// tagged with the older version, so the next Refresh replaces it.
+	version, err := e.store.RouteVersion(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := e.store.ListRoutes(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	fallback, err := e.store.GetRoutingFallback(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if fallback == nil {
+		fallback = &control.RoutingFallback{Mode: control.FallbackDefault}
+	}
+	if t, err = compileTable(version, routes, *fallback); err != nil {
+		return nil, err
+	}
 
-	channels := make([]string, 0, len(routes))
-	for _, r := range routes {
-		channels = append(channels, r.TargetChannel)
+	e.mu.Lock()
+	if e.epoch == epoch {
+		e.tables[tenantID] = t
 	}
-	return channels, nil
+	e.mu.Unlock()
+	return t, nil
 }
@@ -0,0 +1,107 @@
+package routing_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/routing"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/store"
+)
+
+var benchRouteCounts = []int{10, 200, 1000}
+
+// BenchmarkResolveSQLScan is the baseline: the per-event SQL query and
+// linear scan of every route that the compiled tables replaced.
+func BenchmarkResolveSQLScan(b *testing.B) {
+	for _, n := range benchRouteCounts {
+		b.Run(fmt.Sprintf("routes=%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			ctrlStore, env := benchTenant(b, n)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				routes, err := ctrlStore.FindRoutesForEvent(ctx, env.TenantID, env.Type)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, r := range routes {
+					if _, err := routing.ParseFilter(r.Filter); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkResolveCompiled(b *testing.B) {
+	for _, n := range benchRouteCounts {
+		b.Run(fmt.Sprintf("routes=%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			ctrlStore, env := benchTenant(b, n)
+			engine := routing.NewEngine(logger.New("error"), ctrlStore)
+			if _, err := engine.ResolveChannels(ctx, env); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := engine.ResolveChannels(ctx, env); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchTenant creates a tenant with numRoutes routes of mixed match types,
+// most of which miss the returned event.
+func benchTenant(b *testing.B, numRoutes int) (*control.Store, events.EventEnvelope) {
+	b.Helper()
+
+	db, err := store.Open("file:" + filepath.Join(b.TempDir(), "bench.db") + "?_foreign_keys=on")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := store.Migrate(db); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	ctrlStore := control.NewStore(db)
+	tenant, err := ctrlStore.CreateTenant(ctx, "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < numRoutes; i++ {
+		matchType, matchValue := control.MatchExact, fmt.Sprintf("order.%d.created", i)
+		switch i % 4 {
+		case 1:
+			matchType, matchValue = control.MatchPrefix, fmt.Sprintf("invoice.%d.", i)
+		case 2:
+			matchType, matchValue = control.MatchGlob, fmt.Sprintf("user.%d.*", i)
+		case 3:
+			matchType, matchValue = control.MatchRegex, fmt.Sprintf(`payment\.%d\.(failed|refunded)`, i)
+		}
+		if _, err := ctrlStore.CreateRoute(ctx, control.Route{
+			TenantID:      tenant.ID,
+			MatchType:     matchType,
+			MatchValue:    matchValue,
+			TargetChannel: fmt.Sprintf("tenant:%s:c%d", tenant.ID, i),
+			Enabled:       true,
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return ctrlStore, events.EventEnvelope{TenantID: tenant.ID, Type: "order.0.created"}
+}
@@ -0,0 +1,238 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Filter is a boolean expression over an event's data and metadata. A node
+// is either a combinator (all, any, not) or a single field condition:
+//
+//	{"all": [
+//	  {"field": "data.amount", "op": "gt", "value": 10000},
+//	  {"not": {"field": "metadata.test", "op": "eq", "value": true}}
+//	]}
+//
+// Fields are dotted paths rooted at "data." or "metadata.".
+type Filter struct {
+	All   []Filter `json:"all,omitempty"`
+	Any   []Filter `json:"any,omitempty"`
+	Not   *Filter  `json:"not,omitempty"`
+	Field string   `json:"field,omitempty"`
+	Op    string   `json:"op,omitempty"`
+	Value any      `json:"value,omitempty"`
+}
+
+const (
+	OpEq        = "eq"
+	OpNe        = "ne"
+	OpGt        = "gt"
+	OpGte       = "gte"
+	OpLt        = "lt"
+	OpLte       = "lte"
+	OpExists    = "exists"
+	OpNotExists = "not_exists"
+	OpIn        = "in"
+	OpNotIn     = "not_in"
+)
+
+var ErrInvalidFilter = errors.New("invalid route filter")
+
+// ParseFilter decodes and validates a stored filter. An empty input means the
+// route has no filter and yields nil.
+func ParseFilter(raw []byte) (*Filter, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var f Filter
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (f *Filter) validate() error {
+	kinds := 0
+	if f.All != nil {
+		kinds++
+	}
+	if f.Any != nil {
+		kinds++
+	}
+	if f.Not != nil {
+		kinds++
+	}
+	if f.Field != "" || f.Op != "" {
+		kinds++
+	}
+	if kinds != 1 {
+		return fmt.Errorf("%w: each node needs exactly one of all, any, not or field/op", ErrInvalidFilter)
+	}
+
+	switch {
+	case f.All != nil || f.Any != nil:
+		children := f.All
+		if f.Any != nil {
+			children = f.Any
+		}
+		if len(children) == 0 {
+			return fmt.Errorf("%w: all/any must not be empty", ErrInvalidFilter)
+		}
+		for i := range children {
+			if err := children[i].validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case f.Not != nil:
+		return f.Not.validate()
+	}
+
+	if !strings.HasPrefix(f.Field, "data.") && !strings.HasPrefix(f.Field, "metadata.") {
+		return fmt.Errorf("%w: field %q must start with data. or metadata.", ErrInvalidFilter, f.Field)
+	}
+
+	switch f.Op {
+	case OpEq, OpNe:
+	case OpGt, OpGte, OpLt, OpLte:
+		if _, ok := f.Value.(float64); !ok {
+			return fmt.Errorf("%w: %s on %s needs a numeric value", ErrInvalidFilter, f.Op, f.Field)
+		}
+	case OpExists, OpNotExists:
+		if f.Value != nil {
+			return fmt.Errorf("%w: %s on %s takes no value", ErrInvalidFilter, f.Op, f.Field)
+		}
+	case OpIn, OpNotIn:
+		if _, ok := f.Value.([]any); !ok {
+			return fmt.Errorf("%w: %s on %s needs a list value", ErrInvalidFilter, f.Op, f.Field)
+		}
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidFilter, f.Op)
+	}
+	return nil
+}
+
+// Match reports whether an event with the given data and metadata satisfies
+// the filter. Missing fields never satisfy comparisons other than ne,
+// not_exists and not_in.
+func (f *Filter) Match(data, metadata map[string]any) bool {
+	switch {
+	case f.All != nil:
+		for i := range f.All {
+			if !f.All[i].Match(data, metadata) {
+				return false
+			}
+		}
+		return true
+
+	case f.Any != nil:
+		for i := range f.Any {
+			if f.Any[i].Match(data, metadata) {
+				return true
+			}
+		}
+		return false
+
+	case f.Not != nil:
+		return !f.Not.Match(data, metadata)
+	}
+
+	v, ok := lookupField(f.Field, data, metadata)
+
+	switch f.Op {
+	case OpExists:
+		return ok
+	case OpNotExists:
+		return !ok
+	case OpEq:
+		return ok && valuesEqual(v, f.Value)
+	case OpNe:
+		return !ok || !valuesEqual(v, f.Value)
+	case OpIn, OpNotIn:
+		found := false
+		if ok {
+			for _, candidate := range f.Value.([]any) {
+				if valuesEqual(v, candidate) {
+					found = true
+					break
+				}
+			}
+		}
+		return found == (f.Op == OpIn)
+	}
+
+	n, isNum := v.(float64)
+	if !ok || !isNum {
+		return false
+	}
+	want := f.Value.(float64)
+	switch f.Op {
+	case OpGt:
+		return n > want
+	case OpGte:
+		return n >= want
+	case OpLt:
+		return n < want
+	case OpLte:
+		return n <= want
+	}
+	return false
+}
+
+func lookupField(path string, data, metadata map[string]any) (any, bool) {
+	root, rest, _ := strings.Cut(path, ".")
+
+	var cur any
+	switch root {
+	case "data":
+		cur = data
+	case "metadata":
+		cur = metadata
+	default:
+		return nil, false
+	}
+
+	for _, key := range strings.Split(rest, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func valuesEqual(a, b any) bool {
+	if an, ok := toFloat(a); ok {
+		bn, ok := toFloat(b)
+		return ok && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFilterRejects(t *testing.T) {
+	tests := []struct {
+		name, raw string
+	}{
+		{"malformed", `{"field":`},
+		{"empty node", `{}`},
+		{"two kinds", `{"all":[{"field":"data.a","op":"exists"}],"field":"data.a","op":"exists"}`},
+		{"empty all", `{"all":[]}`},
+		{"empty any", `{"any":[]}`},
+		{"bad child", `{"all":[{"field":"data.a","op":"exists"},{"field":"a","op":"exists"}]}`},
+		{"bad not", `{"not":{"op":"eq"}}`},
+		{"field outside roots", `{"field":"source.ip","op":"exists"}`},
+		{"bare root", `{"field":"data","op":"exists"}`},
+		{"unknown op", `{"field":"data.a","op":"contains","value":"x"}`},
+		{"missing op", `{"field":"data.a"}`},
+		{"gt without number", `{"field":"data.a","op":"gt","value":"10"}`},
+		{"lte without value", `{"field":"data.a","op":"lte"}`},
+		{"exists with value", `{"field":"data.a","op":"exists","value":true}`},
+		{"in without list", `{"field":"data.a","op":"in","value":"x"}`},
+		{"not_in without list", `{"field":"data.a","op":"not_in","value":1}`},
+		{"misspelled value", `{"field":"data.a","op":"eq","valeu":"x"}`},
+		{"misspelled field", `{"feild":"data.a","field":"data.b","op":"exists"}`},
+		{"unknown key in child", `{"any":[{"field":"data.a","op":"exists","negate":true}]}`},
+		{"unknown key in not", `{"not":{"field":"data.a","op":"exists","extra":1}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFilter([]byte(tt.raw)); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("ParseFilter(%s) error = %v, want ErrInvalidFilter", tt.raw, err)
+			}
+		})
+	}
+}
+
+func TestParseFilterEmpty(t *testing.T) {
+	for _, raw := range []string{"", "null"} {
+		f, err := ParseFilter([]byte(raw))
+		if f != nil || err != nil {
+			t.Errorf("ParseFilter(%q) = %v, %v, want nil, nil", raw, f, err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	data := map[string]any{
+		"amount":   15000.0,
+		"currency": "EUR",
+		"flag":     false,
+		"tags":     []any{"a", "b"},
+		"customer": map[string]any{"tier": "gold", "id": 42.0},
+		"nothing":  nil,
+	}
+	metadata := map[string]any{"test": true, "region": "eu"}
+
+	tests := []struct {
+		name, filter string
+		want         bool
+	}{
+		{"eq string", `{"field":"data.currency","op":"eq","value":"EUR"}`, true},
+		{"eq string mismatch", `{"field":"data.currency","op":"eq","value":"USD"}`, false},
+		{"eq number", `{"field":"data.customer.id","op":"eq","value":42}`, true},
+		{"eq bool", `{"field":"data.flag","op":"eq","value":false}`, true},
+		{"eq null", `{"field":"data.nothing","op":"eq","value":null}`, true},
+		{"eq list", `{"field":"data.tags","op":"eq","value":["a","b"]}`, true},
+		{"eq missing", `{"field":"data.missing","op":"eq","value":null}`, false},
+		{"eq metadata", `{"field":"metadata.test","op":"eq","value":true}`, true},
+		{"ne", `{"field":"data.currency","op":"ne","value":"USD"}`, true},
+		{"ne equal", `{"field":"data.currency","op":"ne","value":"EUR"}`, false},
+		{"ne missing", `{"field":"data.missing","op":"ne","value":"x"}`, true},
+		{"gt", `{"field":"data.amount","op":"gt","value":10000}`, true},
+		{"gt equal", `{"field":"data.amount","op":"gt","value":15000}`, false},
+		{"gte equal", `{"field":"data.amount","op":"gte","value":15000}`, true},
+		{"lt", `{"field":"data.amount","op":"lt","value":15000}`, false},
+		{"lte equal", `{"field":"data.amount","op":"lte","value":15000}`, true},
+		{"gt on string", `{"field":"data.currency","op":"gt","value":0}`, false},
+		{"lt missing", `{"field":"data.missing","op":"lt","value":1}`, false},
+		{"exists", `{"field":"data.customer.tier","op":"exists"}`, true},
+		{"exists null value", `{"field":"data.nothing","op":"exists"}`, true},
+		{"exists missing", `{"field":"data.customer.name","op":"exists"}`, false},
+		{"exists through scalar", `{"field":"data.currency.code","op":"exists"}`, false},
+		{"not_exists", `{"field":"metadata.missing","op":"not_exists"}`, true},
+		{"not_exists present", `{"field":"metadata.region","op":"not_exists"}`, false},
+		{"in", `{"field":"data.customer.tier","op":"in","value":["gold","platinum"]}`, true},
+		{"in number", `{"field":"data.customer.id","op":"in","value":[1,42]}`, true},
+		{"in mismatch", `{"field":"data.customer.tier","op":"in","value":["silver"]}`, false},
+		{"in missing", `{"field":"data.missing","op":"in","value":["x"]}`, false},
+		{"not_in", `{"field":"metadata.region","op":"not_in","value":["us"]}`, true},
+		{"not_in listed", `{"field":"metadata.region","op":"not_in","value":["eu"]}`, false},
+		{"not_in missing", `{"field":"data.missing","op":"not_in","value":["x"]}`, true},
+		{"all", `{"all":[{"field":"data.amount","op":"gt","value":1},{"field":"metadata.region","op":"eq","value":"eu"}]}`, true},
+		{"all one false", `{"all":[{"field":"data.amount","op":"gt","value":1},{"field":"metadata.region","op":"eq","value":"us"}]}`, false},
+		{"any", `{"any":[{"field":"data.amount","op":"lt","value":1},{"field":"metadata.region","op":"eq","value":"eu"}]}`, true},
+		{"any none", `{"any":[{"field":"data.amount","op":"lt","value":1},{"field":"metadata.region","op":"eq","value":"us"}]}`, false},
+		{"not", `{"not":{"field":"metadata.test","op":"eq","value":true}}`, false},
+		{"nested", `{"all":[{"any":[{"field":"data.currency","op":"eq","value":"USD"},{"field":"data.amount","op":"gte","value":10000}]},{"not":{"field":"data.customer.tier","op":"eq","value":"bronze"}}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilter([]byte(tt.filter))
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if got := f.Match(data, metadata); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatchNilMaps(t *testing.T) {
+	f, err := ParseFilter([]byte(`{"field":"data.a","op":"not_exists"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match(nil, nil) {
+		t.Error("Match(nil, nil) = false, want true")
+	}
+}
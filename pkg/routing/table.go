@@ -0,0 +1,182 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+)
+
+type compiledRoute struct {
+	route     control.Route
+	filter    *Filter
+	transform *Transform
+	order     int
+}
+
+// table is a tenant's routes compiled for lookup by event type: exact
+// matches by hash, prefixes in a byte trie, and GLOB/REGEX patterns tried in
+// turn. Tables are immutable once built and replaced wholesale on change.
+type table struct {
+	version  int64
+	exact    map[string][]*compiledRoute
+	prefixes *trieNode
+	patterns []patternRoute
+	fallback control.RoutingFallback
+}
+
+type patternRoute struct {
+	matcher control.Matcher
+	route   *compiledRoute
+}
+
+// compileTable expects routes in evaluation order, as ListRoutes returns them.
+func compileTable(version int64, routes []control.Route, fallback control.RoutingFallback) (*table, error) {
+	t := &table{
+		version:  version,
+		exact:    make(map[string][]*compiledRoute),
+		prefixes: &trieNode{},
+		fallback: fallback,
+	}
+
+	for i, r := range routes {
+		if !r.Enabled {
+			continue
+		}
+		filter, err := ParseFilter(r.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", r.ID, err)
+		}
+		transform, err := ParseTransform(r.Transform)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", r.ID, err)
+		}
+		cr := &compiledRoute{route: r, filter: filter, transform: transform, order: i}
+
+		switch r.MatchType {
+		case control.MatchExact:
+			t.exact[r.MatchValue] = append(t.exact[r.MatchValue], cr)
+		case control.MatchPrefix:
+			t.prefixes.insert(r.MatchValue, cr)
+		default:
+			m, err := control.CompileMatcher(r.MatchType, r.MatchValue)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: %w", r.ID, err)
+			}
+			t.patterns = append(t.patterns, patternRoute{matcher: m, route: cr})
+		}
+	}
+	return t, nil
+}
+
+// Target is one destination of a routed event: a realtime channel or a
+// webhook target, and the transform applied on the way.
+type Target struct {
+	Channel   string
+	Transform *Transform
+}
+
+// Event returns env as it should be delivered to this target.
+func (t Target) Event(env events.EventEnvelope) events.EventEnvelope {
+	if t.Transform == nil {
+		return env
+	}
+	return t.Transform.Apply(env)
+}
+
+// Resolution is the outcome of routing one event.
+type Resolution struct {
+	// Matched lists the routes whose rule and filter accepted the event,
+	// in evaluation order, ending early at a StopProcessing route.
+	Matched []control.Route
+	Targets []Target
+	// Fallback is set when no route matched and Targets came from the
+	// tenant's fallback.
+	Fallback bool
+}
+
+func (r Resolution) Channels() []string {
+	out := make([]string, 0, len(r.Targets))
+	for _, t := range r.Targets {
+		out = append(out, t.Channel)
+	}
+	return out
+}
+
+func (t *table) resolve(env events.EventEnvelope) Resolution {
+	var res Resolution
+	for _, r := range t.match(env.Type) {
+		if r.filter != nil && !r.filter.Match(env.Data, env.Metadata) {
+			continue
+		}
+		res.Matched = append(res.Matched, r.route)
+		res.Targets = append(res.Targets, Target{Channel: r.route.TargetChannel, Transform: r.transform})
+		if r.route.StopProcessing {
+			break
+		}
+	}
+	if len(res.Matched) > 0 {
+		return res
+	}
+
+	res.Fallback = true
+	switch t.fallback.Mode {
+	case control.FallbackDrop:
+	case control.FallbackChannel:
+		res.Targets = []Target{{Channel: t.fallback.Channel}}
+	default:
+		res.Targets = []Target{{Channel: DefaultChannel(env.TenantID)}}
+	}
+	return res
+}
+
+// match returns the routes whose match rule accepts eventType, in evaluation
+// order.
+func (t *table) match(eventType string) []*compiledRoute {
+	out := append([]*compiledRoute(nil), t.exact[eventType]...)
+	out = t.prefixes.collect(eventType, out)
+	for _, p := range t.patterns {
+		if p.matcher.Match(eventType) {
+			out = append(out, p.route)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].order < out[j].order })
+	return out
+}
+
+type trieNode struct {
+	children map[byte]*trieNode
+	routes   []*compiledRoute
+}
+
+func (n *trieNode) insert(prefix string, r *compiledRoute) {
+	node := n
+	for i := 0; i < len(prefix); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*trieNode)
+		}
+		next, ok := node.children[prefix[i]]
+		if !ok {
+			next = &trieNode{}
+			node.children[prefix[i]] = next
+		}
+		node = next
+	}
+	node.routes = append(node.routes, r)
+}
+
+// collect appends the routes of every prefix of s stored in the trie.
+func (n *trieNode) collect(s string, out []*compiledRoute) []*compiledRoute {
+	node := n
+	for i := 0; ; i++ {
+		out = append(out, node.routes...)
+		if i == len(s) {
+			return out
+		}
+		if node = node.children[s[i]]; node == nil {
+			return out
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+)
+
+// Transform reshapes an event's data and metadata for one route's target.
+// Paths are dotted and rooted at "data" or "metadata"; the steps run in
+// field order:
+//
+//	{"include":  ["data.order_id", "data.total"],
+//	 "exclude":  ["data.total.tax"],
+//	 "rename":   {"data.total": "data.amount"},
+//	 "set":      {"metadata.visibility": "public"},
+//	 "template": {"data.summary": "order {{data.order_id}} by {{metadata.user.name}}"}}
+//
+// When include is set, anything under data and metadata it does not list
+// is dropped. Templates read the event as it was ingested; a template that
+// is exactly one placeholder keeps the referenced value's JSON type.
+type Transform struct {
+	Include  []string          `json:"include,omitempty"`
+	Exclude  []string          `json:"exclude,omitempty"`
+	Rename   map[string]string `json:"rename,omitempty"`
+	Set      map[string]any    `json:"set,omitempty"`
+	Template map[string]string `json:"template,omitempty"`
+}
+
+var ErrInvalidTransform = errors.New("invalid route transform")
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// ParseTransform decodes and validates a stored transform. An empty input
+// means the route delivers events unchanged and yields nil.
+func ParseTransform(raw []byte) (*Transform, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var t Transform
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransform, err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (t *Transform) validate() error {
+	for _, p := range t.Include {
+		if err := validatePath(p, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range t.Exclude {
+		if err := validatePath(p, true); err != nil {
+			return err
+		}
+	}
+	for from, to := range t.Rename {
+		if err := validatePath(from, false); err != nil {
+			return err
+		}
+		if err := validatePath(to, false); err != nil {
+			return err
+		}
+	}
+	for p := range t.Set {
+		if err := validatePath(p, false); err != nil {
+			return err
+		}
+	}
+	for p, tmpl := range t.Template {
+		if err := validatePath(p, false); err != nil {
+			return err
+		}
+		for _, m := range placeholderRe.FindAllStringSubmatch(tmpl, -1) {
+			switch m[1] {
+			case "id", "type", "tenant_id":
+				continue
+			}
+			if err := validatePath(m[1], false); err != nil {
+				return fmt.Errorf("%w: template for %s references %q", ErrInvalidTransform, p, m[1])
+			}
+		}
+	}
+	return nil
+}
+
+func validatePath(p string, allowRoot bool) error {
+	root, rest, hasRest := strings.Cut(p, ".")
+	if root != "data" && root != "metadata" {
+		return fmt.Errorf("%w: path %q must start with data or metadata", ErrInvalidTransform, p)
+	}
+	if !hasRest {
+		if allowRoot {
+			return nil
+		}
+		return fmt.Errorf("%w: path %q needs a field below %s", ErrInvalidTransform, p, root)
+	}
+	for _, seg := range strings.Split(rest, ".") {
+		if seg == "" {
+			return fmt.Errorf("%w: path %q has an empty segment", ErrInvalidTransform, p)
+		}
+	}
+	return nil
+}
+
+// Apply returns a transformed copy of env; env itself is not modified.
+func (t *Transform) Apply(env events.EventEnvelope) events.EventEnvelope {
+	doc := map[string]any{
+		"data":     copyValue(map[string]any(env.Data)),
+		"metadata": copyValue(map[string]any(env.Metadata)),
+	}
+
+	if len(t.Include) > 0 {
+		kept := map[string]any{"data": map[string]any{}, "metadata": map[string]any{}}
+		for _, p := range t.Include {
+			if v, ok := getPath(doc, p); ok {
+				setPath(kept, p, v)
+			}
+		}
+		doc = kept
+	}
+	for _, p := range t.Exclude {
+		deletePath(doc, p)
+	}
+	for _, from := range sortedKeys(t.Rename) {
+		if v, ok := getPath(doc, from); ok {
+			deletePath(doc, from)
+			setPath(doc, t.Rename[from], v)
+		}
+	}
+	for _, p := range sortedKeys(t.Set) {
+		setPath(doc, p, copyValue(t.Set[p]))
+	}
+	for _, p := range sortedKeys(t.Template) {
+		setPath(doc, p, render(t.Template[p], env))
+	}
+
+	out := env
+	out.Data = rootMap(doc, "data", env.Data == nil)
+	out.Metadata = rootMap(doc, "metadata", env.Metadata == nil)
+	return out
+}
+
+func render(tmpl string, env events.EventEnvelope) any {
+	if m := placeholderRe.FindStringSubmatch(tmpl); m != nil && m[0] == tmpl {
+		v, _ := lookupEnvelope(m[1], env)
+		return v
+	}
+	return placeholderRe.ReplaceAllStringFunc(tmpl, func(s string) string {
+		v, ok := lookupEnvelope(placeholderRe.FindStringSubmatch(s)[1], env)
+		if !ok || v == nil {
+			return ""
+		}
+		if str, isStr := v.(string); isStr {
+			return str
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	})
+}
+
+func lookupEnvelope(path string, env events.EventEnvelope) (any, bool) {
+	switch path {
+	case "id":
+		return env.ID, true
+	case "type":
+		return env.Type, true
+	case "tenant_id":
+		return env.TenantID, true
+	}
+	return lookupField(path, env.Data, env.Metadata)
+}
+
+func getPath(doc map[string]any, path string) (any, bool) {
+	var cur any = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setPath creates intermediate objects as needed, replacing any non-object
+// value in the way.
+func setPath(doc map[string]any, path string, v any) {
+	keys := strings.Split(path, ".")
+	m := doc
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = v
+}
+
+func deletePath(doc map[string]any, path string) {
+	if root, _, hasRest := strings.Cut(path, "."); !hasRest {
+		doc[root] = map[string]any{}
+		return
+	}
+	keys := strings.Split(path, ".")
+	parent, ok := getPath(doc, strings.Join(keys[:len(keys)-1], "."))
+	if m, isMap := parent.(map[string]any); ok && isMap {
+		delete(m, keys[len(keys)-1])
+	}
+}
+
+func rootMap(doc map[string]any, root string, wasNil bool) map[string]any {
+	m, _ := doc[root].(map[string]any)
+	if len(m) == 0 && wasNil {
+		return nil
+	}
+	return m
+}
+
+func copyValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		if t == nil {
+			return map[string]any{}
+		}
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = copyValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = copyValue(val)
+		}
+		return out
+	}
+	return v
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
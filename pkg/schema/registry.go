@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
+)
+
+// Registry checks events against their tenant's active schemas. Each
+// tenant's schemas and mode are cached and reloaded once older than the
+// refresh interval, which bounds how long a registration takes to reach a
+// running gateway.
+type Registry struct {
+	store   *control.Store
+	refresh time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]cachedTenant
+}
+
+type cachedTenant struct {
+	mode    string
+	schemas map[string]activeSchema // by event type
+	loaded  time.Time
+}
+
+type activeSchema struct {
+	version int
+	schema  *Schema
+}
+
+// Result is the outcome of checking one event against its active schema.
+type Result struct {
+	Version int
+	Errors  []FieldError
+	// Reject is set when the event is invalid and the tenant refuses
+	// invalid events rather than tagging them.
+	Reject bool
+}
+
+func (r *Result) Valid() bool { return len(r.Errors) == 0 }
+
+func NewRegistry(store *control.Store, refresh time.Duration) *Registry {
+	return &Registry{
+		store:   store,
+		refresh: refresh,
+		entries: make(map[string]cachedTenant),
+	}
+}
+
+// Check validates data against the active schema for eventType. It returns
+// nil when the type has no active schema.
+func (r *Registry) Check(ctx context.Context, tenantID, eventType string, data map[string]any) (*Result, error) {
+	entry, err := r.tenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	active, ok := entry.schemas[eventType]
+	if !ok {
+		return nil, nil
+	}
+	res := &Result{Version: active.version, Errors: active.schema.Validate(data)}
+	res.Reject = !res.Valid() && entry.mode != control.SchemaModeTag
+	return res, nil
+}
+
+func (r *Registry) tenant(ctx context.Context, tenantID string) (cachedTenant, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[tenantID]
+	r.mu.RUnlock()
+	if ok && time.Since(entry.loaded) < r.refresh {
+		return entry, nil
+	}
+
+	mode, err := r.store.GetSchemaMode(ctx, tenantID)
+	if err != nil {
+		return cachedTenant{}, fmt.Errorf("load schemas: %w", err)
+	}
+	stored, err := r.store.ActiveSchemas(ctx, tenantID)
+	if err != nil {
+		return cachedTenant{}, fmt.Errorf("load schemas: %w", err)
+	}
+	entry = cachedTenant{
+		mode:    mode,
+		schemas: make(map[string]activeSchema, len(stored)),
+		loaded:  time.Now(),
+	}
+	for _, es := range stored {
+		s, err := Compile(es.Schema)
+		if err != nil {
+			return cachedTenant{}, fmt.Errorf("load schema %s v%d: %w", es.EventType, es.Version, err)
+		}
+		entry.schemas[es.EventType] = activeSchema{version: es.Version, schema: s}
+	}
+
+	r.mu.Lock()
+	r.entries[tenantID] = entry
+	r.mu.Unlock()
+	return entry, nil
+}
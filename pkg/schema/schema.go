@@ -0,0 +1,460 @@
+// Package schema validates event data against JSON Schemas registered per
+// tenant and event type. It supports the subset of JSON Schema that event
+// payloads need; a schema using any other keyword is rejected when compiled
+// rather than silently ignored.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// FieldError reports one violation. Field is a dotted path rooted at "data",
+// with array elements addressed by index: data.items.0.sku.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Schema is a compiled JSON Schema supporting type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, allOf, anyOf, oneOf and not. Annotation keywords such as
+// title and description are accepted and ignored.
+type Schema struct {
+	types    []string
+	enum     []any
+	constVal any
+	hasConst bool
+
+	properties   map[string]*Schema
+	required     []string
+	additional   *Schema
+	noAdditional bool
+	items        *Schema
+	minItems     *int
+	maxItems     *int
+	minLength    *int
+	maxLength    *int
+	pattern      *regexp.Regexp
+	minimum      *float64
+	maximum      *float64
+	exclusiveMin *float64
+	exclusiveMax *float64
+	allOf        []*Schema
+	anyOf        []*Schema
+	oneOf        []*Schema
+	not          *Schema
+	alwaysFails  bool // the boolean schema false
+}
+
+var validTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// Compile parses and checks a schema document.
+func Compile(raw []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, fmt.Errorf("%w: schema must be a JSON object", ErrInvalidSchema)
+	}
+	return compile(doc, "#")
+}
+
+func compile(doc any, at string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{alwaysFails: !b}, nil
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be an object or boolean", ErrInvalidSchema, at)
+	}
+
+	s := &Schema{}
+	for _, key := range sortedKeys(m) {
+		v := m[key]
+		where := at + "/" + key
+		var err error
+
+		switch key {
+		case "type":
+			s.types, err = compileTypes(v, where)
+		case "enum":
+			list, ok := v.([]any)
+			if !ok || len(list) == 0 {
+				err = fmt.Errorf("%w: %s must be a non-empty array", ErrInvalidSchema, where)
+			}
+			s.enum = normalize(list).([]any)
+		case "const":
+			s.constVal, s.hasConst = normalize(v), true
+		case "properties":
+			props, ok := v.(map[string]any)
+			if !ok {
+				err = fmt.Errorf("%w: %s must be an object", ErrInvalidSchema, where)
+				break
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				if s.properties[name], err = compile(sub, where+"/"+name); err != nil {
+					break
+				}
+			}
+		case "required":
+			s.required, err = compileStrings(v, where)
+		case "additionalProperties":
+			if b, ok := v.(bool); ok {
+				s.noAdditional = !b
+				break
+			}
+			s.additional, err = compile(v, where)
+		case "items":
+			s.items, err = compile(v, where)
+		case "minItems":
+			s.minItems, err = compileCount(v, where)
+		case "maxItems":
+			s.maxItems, err = compileCount(v, where)
+		case "minLength":
+			s.minLength, err = compileCount(v, where)
+		case "maxLength":
+			s.maxLength, err = compileCount(v, where)
+		case "pattern":
+			p, ok := v.(string)
+			if !ok {
+				err = fmt.Errorf("%w: %s must be a string", ErrInvalidSchema, where)
+				break
+			}
+			if s.pattern, err = regexp.Compile(p); err != nil {
+				err = fmt.Errorf("%w: %s: %v", ErrInvalidSchema, where, err)
+			}
+		case "minimum":
+			s.minimum, err = compileNumber(v, where)
+		case "maximum":
+			s.maximum, err = compileNumber(v, where)
+		case "exclusiveMinimum":
+			s.exclusiveMin, err = compileNumber(v, where)
+		case "exclusiveMaximum":
+			s.exclusiveMax, err = compileNumber(v, where)
+		case "allOf":
+			s.allOf, err = compileList(v, where)
+		case "anyOf":
+			s.anyOf, err = compileList(v, where)
+		case "oneOf":
+			s.oneOf, err = compileList(v, where)
+		case "not":
+			s.not, err = compile(v, where)
+		default:
+			if !annotations[key] {
+				err = fmt.Errorf("%w: unsupported keyword %s", ErrInvalidSchema, where)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(v any, at string) ([]string, error) {
+	var names []string
+	switch t := v.(type) {
+	case string:
+		names = []string{t}
+	case []any:
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must list type names", ErrInvalidSchema, at)
+			}
+			names = append(names, name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s must be a string or array", ErrInvalidSchema, at)
+	}
+	for _, name := range names {
+		if !validTypes[name] {
+			return nil, fmt.Errorf("%w: %s has unknown type %q", ErrInvalidSchema, at, name)
+		}
+	}
+	return names, nil
+}
+
+func compileStrings(v any, at string) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be an array of strings", ErrInvalidSchema, at)
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be an array of strings", ErrInvalidSchema, at)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func compileCount(v any, at string) (*int, error) {
+	n, ok := v.(json.Number)
+	if ok {
+		if i, err := strconv.Atoi(n.String()); err == nil && i >= 0 {
+			return &i, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s must be a non-negative integer", ErrInvalidSchema, at)
+}
+
+func compileNumber(v any, at string) (*float64, error) {
+	n, ok := v.(json.Number)
+	if ok {
+		if f, err := n.Float64(); err == nil {
+			return &f, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidSchema, at)
+}
+
+func compileList(v any, at string) ([]*Schema, error) {
+	list, ok := v.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%w: %s must be a non-empty array", ErrInvalidSchema, at)
+	}
+	out := make([]*Schema, len(list))
+	for i, item := range list {
+		var err error
+		if out[i], err = compile(item, fmt.Sprintf("%s/%d", at, i)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Validate checks data, the event's data object, and returns every violation
+// found. A nil data is validated as an empty object.
+func (s *Schema) Validate(data map[string]any) []FieldError {
+	var doc any = map[string]any{}
+	if data != nil {
+		doc = normalize(data)
+	}
+	var errs []FieldError
+	s.validate(doc, "data", &errs)
+	return errs
+}
+
+func (s *Schema) validate(v any, path string, errs *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.alwaysFails {
+		fail("is not allowed")
+		return
+	}
+	if len(s.types) > 0 && !s.matchesType(v) {
+		fail("must be of type %s", strings.Join(s.types, " or "))
+		// Further keywords would only repeat the type mismatch.
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, v) {
+		fail("must be one of the allowed values")
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constVal, v) {
+		fail("must equal the constant value")
+	}
+
+	switch t := v.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := t[name]; !ok {
+				*errs = append(*errs, FieldError{Field: path + "." + name, Message: "is required"})
+			}
+		}
+		for _, name := range sortedKeys(t) {
+			if sub, ok := s.properties[name]; ok {
+				sub.validate(t[name], path+"."+name, errs)
+				continue
+			}
+			if s.noAdditional {
+				*errs = append(*errs, FieldError{Field: path + "." + name, Message: "is not an allowed property"})
+			} else if s.additional != nil {
+				s.additional.validate(t[name], path+"."+name, errs)
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(t) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(t) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range t {
+				s.items.validate(item, path+"."+strconv.Itoa(i), errs)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(t)
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(t) {
+			fail("must match pattern %s", s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && t < *s.minimum {
+			fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && t > *s.maximum {
+			fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMin != nil && t <= *s.exclusiveMin {
+			fail("must be > %v", *s.exclusiveMin)
+		}
+		if s.exclusiveMax != nil && t >= *s.exclusiveMax {
+			fail("must be < %v", *s.exclusiveMax)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, path, errs)
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.matches(v, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must match at least one of the allowed schemas")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		n := 0
+		for _, sub := range s.oneOf {
+			if sub.matches(v, path) {
+				n++
+			}
+		}
+		if n != 1 {
+			fail("must match exactly one of the allowed schemas, matched %d", n)
+		}
+	}
+	if s.not != nil && s.not.matches(v, path) {
+		fail("must not match the excluded schema")
+	}
+}
+
+func (s *Schema) matches(v any, path string) bool {
+	var errs []FieldError
+	s.validate(v, path, &errs)
+	return len(errs) == 0
+}
+
+func (s *Schema) matchesType(v any) bool {
+	for _, t := range s.types {
+		switch t {
+		case "object":
+			if _, ok := v.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]any); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalize converts numbers to float64 so values decoded with and without
+// UseNumber compare equal.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = normalize(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = normalize(val)
+		}
+		return out
+	}
+	return v
+}
+
+func containsValue(list []any, v any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,224 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"malformed json", `{"type":`, "unexpected EOF"},
+		{"not an object", `["object"]`, "schema must be a JSON object"},
+		{"boolean root", `true`, "schema must be a JSON object"},
+		{"unknown keyword", `{"$ref":"#/defs/x"}`, "unsupported keyword #/$ref"},
+		{"nested unknown keyword", `{"properties":{"a":{"format":"email"}}}`, "unsupported keyword #/properties/a/format"},
+		{"type not string or array", `{"type":1}`, "#/type must be a string or array"},
+		{"type list with non-string", `{"type":["string",1]}`, "#/type must list type names"},
+		{"unknown type", `{"type":"date"}`, `#/type has unknown type "date"`},
+		{"enum not array", `{"enum":"a"}`, "#/enum must be a non-empty array"},
+		{"enum empty", `{"enum":[]}`, "#/enum must be a non-empty array"},
+		{"properties not object", `{"properties":[]}`, "#/properties must be an object"},
+		{"property schema not object", `{"properties":{"a":1}}`, "#/properties/a must be an object or boolean"},
+		{"required not array", `{"required":"a"}`, "#/required must be an array of strings"},
+		{"required with non-string", `{"required":["a",1]}`, "#/required must be an array of strings"},
+		{"additionalProperties invalid", `{"additionalProperties":"no"}`, "#/additionalProperties must be an object or boolean"},
+		{"items invalid", `{"items":[{"type":"string"}]}`, "#/items must be an object or boolean"},
+		{"minItems negative", `{"minItems":-1}`, "#/minItems must be a non-negative integer"},
+		{"maxItems fractional", `{"maxItems":1.5}`, "#/maxItems must be a non-negative integer"},
+		{"minLength string", `{"minLength":"1"}`, "#/minLength must be a non-negative integer"},
+		{"maxLength negative", `{"maxLength":-2}`, "#/maxLength must be a non-negative integer"},
+		{"pattern not string", `{"pattern":1}`, "#/pattern must be a string"},
+		{"pattern invalid regexp", `{"pattern":"("}`, "#/pattern: error parsing regexp"},
+		{"minimum string", `{"minimum":"0"}`, "#/minimum must be a number"},
+		{"maximum bool", `{"maximum":true}`, "#/maximum must be a number"},
+		{"exclusiveMinimum bool", `{"exclusiveMinimum":true}`, "#/exclusiveMinimum must be a number"},
+		{"exclusiveMaximum null", `{"exclusiveMaximum":null}`, "#/exclusiveMaximum must be a number"},
+		{"allOf empty", `{"allOf":[]}`, "#/allOf must be a non-empty array"},
+		{"anyOf not array", `{"anyOf":{}}`, "#/anyOf must be a non-empty array"},
+		{"oneOf invalid member", `{"oneOf":[{"type":"string"},{"minLength":-1}]}`, "#/oneOf/1/minLength must be a non-negative integer"},
+		{"not invalid", `{"not":"x"}`, "#/not must be an object or boolean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if !errors.Is(err, ErrInvalidSchema) {
+				t.Fatalf("Compile(%s) error = %v, want ErrInvalidSchema", tt.schema, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile(%s) error = %q, want it to contain %q", tt.schema, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileAcceptsAnnotations(t *testing.T) {
+	raw := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id": "order", "$comment": "c", "title": "Order", "description": "d",
+		"default": {}, "examples": [{}], "deprecated": false, "readOnly": false, "writeOnly": false,
+		"type": "object"
+	}`
+	if _, err := Compile([]byte(raw)); err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		want   []FieldError
+	}{
+		// type
+		{"type match", `{"properties":{"a":{"type":"string"}}}`, `{"a":"x"}`, nil},
+		{"type mismatch", `{"properties":{"a":{"type":"string"}}}`, `{"a":1}`,
+			[]FieldError{{"data.a", "must be of type string"}}},
+		{"type list", `{"properties":{"a":{"type":["string","null"]}}}`, `{"a":null}`, nil},
+		{"type list mismatch", `{"properties":{"a":{"type":["string","null"]}}}`, `{"a":true}`,
+			[]FieldError{{"data.a", "must be of type string or null"}}},
+		{"integer accepts whole number", `{"properties":{"a":{"type":"integer"}}}`, `{"a":3.0}`, nil},
+		{"integer rejects fraction", `{"properties":{"a":{"type":"integer"}}}`, `{"a":3.5}`,
+			[]FieldError{{"data.a", "must be of type integer"}}},
+		{"number", `{"properties":{"a":{"type":"number"}}}`, `{"a":3.5}`, nil},
+		{"boolean", `{"properties":{"a":{"type":"boolean"}}}`, `{"a":"true"}`,
+			[]FieldError{{"data.a", "must be of type boolean"}}},
+		{"object", `{"properties":{"a":{"type":"object"}}}`, `{"a":[]}`,
+			[]FieldError{{"data.a", "must be of type object"}}},
+		{"array", `{"properties":{"a":{"type":"array"}}}`, `{"a":{}}`,
+			[]FieldError{{"data.a", "must be of type array"}}},
+		{"type mismatch stops other keywords", `{"properties":{"a":{"type":"string","minimum":5}}}`, `{"a":1}`,
+			[]FieldError{{"data.a", "must be of type string"}}},
+
+		// enum and const
+		{"enum match", `{"properties":{"a":{"enum":["x",1]}}}`, `{"a":1}`, nil},
+		{"enum mismatch", `{"properties":{"a":{"enum":["x",1]}}}`, `{"a":"y"}`,
+			[]FieldError{{"data.a", "must be one of the allowed values"}}},
+		{"enum object", `{"properties":{"a":{"enum":[{"k":1}]}}}`, `{"a":{"k":1}}`, nil},
+		{"const match", `{"properties":{"a":{"const":2}}}`, `{"a":2}`, nil},
+		{"const mismatch", `{"properties":{"a":{"const":2}}}`, `{"a":3}`,
+			[]FieldError{{"data.a", "must equal the constant value"}}},
+		{"const null", `{"properties":{"a":{"const":null}}}`, `{"a":0}`,
+			[]FieldError{{"data.a", "must equal the constant value"}}},
+
+		// objects
+		{"required present", `{"required":["a"]}`, `{"a":1}`, nil},
+		{"required missing", `{"required":["a","b"]}`, `{"b":null}`,
+			[]FieldError{{"data.a", "is required"}}},
+		{"additionalProperties false", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"b":2}`,
+			[]FieldError{{"data.b", "is not an allowed property"}}},
+		{"additionalProperties true", `{"properties":{"a":{}},"additionalProperties":true}`, `{"b":2}`, nil},
+		{"additionalProperties schema", `{"properties":{"a":{}},"additionalProperties":{"type":"string"}}`, `{"a":1,"b":2}`,
+			[]FieldError{{"data.b", "must be of type string"}}},
+		{"nested path", `{"properties":{"a":{"properties":{"b":{"required":["c"]}}}}}`, `{"a":{"b":{}}}`,
+			[]FieldError{{"data.a.b.c", "is required"}}},
+		{"false property schema", `{"properties":{"a":false}}`, `{"a":1}`,
+			[]FieldError{{"data.a", "is not allowed"}}},
+
+		// arrays
+		{"minItems", `{"properties":{"a":{"minItems":2}}}`, `{"a":[1]}`,
+			[]FieldError{{"data.a", "must have at least 2 items"}}},
+		{"maxItems", `{"properties":{"a":{"maxItems":1}}}`, `{"a":[1,2]}`,
+			[]FieldError{{"data.a", "must have at most 1 items"}}},
+		{"items", `{"properties":{"a":{"items":{"type":"string"}}}}`, `{"a":["x",2,"y",false]}`,
+			[]FieldError{{"data.a.1", "must be of type string"}, {"data.a.3", "must be of type string"}}},
+		{"items nested", `{"properties":{"a":{"items":{"required":["sku"]}}}}`, `{"a":[{"sku":1},{}]}`,
+			[]FieldError{{"data.a.1.sku", "is required"}}},
+
+		// strings
+		{"minLength counts runes", `{"properties":{"a":{"minLength":2}}}`, `{"a":"é"}`,
+			[]FieldError{{"data.a", "must be at least 2 characters"}}},
+		{"maxLength counts runes", `{"properties":{"a":{"maxLength":2}}}`, `{"a":"éé"}`, nil},
+		{"maxLength", `{"properties":{"a":{"maxLength":2}}}`, `{"a":"abc"}`,
+			[]FieldError{{"data.a", "must be at most 2 characters"}}},
+		{"pattern match", `{"properties":{"a":{"pattern":"^[A-Z]{3}$"}}}`, `{"a":"USD"}`, nil},
+		{"pattern mismatch", `{"properties":{"a":{"pattern":"^[A-Z]{3}$"}}}`, `{"a":"usd"}`,
+			[]FieldError{{"data.a", "must match pattern ^[A-Z]{3}$"}}},
+		{"string keywords ignore other types", `{"properties":{"a":{"minLength":5}}}`, `{"a":1}`, nil},
+
+		// numbers
+		{"minimum inclusive", `{"properties":{"a":{"minimum":1}}}`, `{"a":1}`, nil},
+		{"minimum", `{"properties":{"a":{"minimum":1}}}`, `{"a":0.5}`,
+			[]FieldError{{"data.a", "must be >= 1"}}},
+		{"maximum", `{"properties":{"a":{"maximum":10}}}`, `{"a":11}`,
+			[]FieldError{{"data.a", "must be <= 10"}}},
+		{"exclusiveMinimum", `{"properties":{"a":{"exclusiveMinimum":0}}}`, `{"a":0}`,
+			[]FieldError{{"data.a", "must be > 0"}}},
+		{"exclusiveMaximum", `{"properties":{"a":{"exclusiveMaximum":1}}}`, `{"a":1}`,
+			[]FieldError{{"data.a", "must be < 1"}}},
+		{"number keywords ignore other types", `{"properties":{"a":{"minimum":5}}}`, `{"a":"1"}`, nil},
+
+		// combinators
+		{"allOf reports each failure", `{"properties":{"a":{"allOf":[{"minimum":5},{"maximum":1}]}}}`, `{"a":3}`,
+			[]FieldError{{"data.a", "must be >= 5"}, {"data.a", "must be <= 1"}}},
+		{"anyOf match", `{"properties":{"a":{"anyOf":[{"type":"string"},{"type":"number"}]}}}`, `{"a":1}`, nil},
+		{"anyOf mismatch", `{"properties":{"a":{"anyOf":[{"type":"string"},{"type":"number"}]}}}`, `{"a":true}`,
+			[]FieldError{{"data.a", "must match at least one of the allowed schemas"}}},
+		{"oneOf match", `{"properties":{"a":{"oneOf":[{"type":"string"},{"type":"number"}]}}}`, `{"a":1}`, nil},
+		{"oneOf none", `{"properties":{"a":{"oneOf":[{"type":"string"},{"type":"number"}]}}}`, `{"a":null}`,
+			[]FieldError{{"data.a", "must match exactly one of the allowed schemas, matched 0"}}},
+		{"oneOf several", `{"properties":{"a":{"oneOf":[{"type":"number"},{"type":"integer"}]}}}`, `{"a":1}`,
+			[]FieldError{{"data.a", "must match exactly one of the allowed schemas, matched 2"}}},
+		{"not", `{"properties":{"a":{"not":{"type":"null"}}}}`, `{"a":null}`,
+			[]FieldError{{"data.a", "must not match the excluded schema"}}},
+		{"not passes", `{"properties":{"a":{"not":{"type":"null"}}}}`, `{"a":1}`, nil},
+
+		// several violations at once
+		{"collects every violation", `{
+			"type": "object",
+			"required": ["id"],
+			"properties": {"total": {"type": "number", "minimum": 0}, "tags": {"maxItems": 1}},
+			"additionalProperties": false
+		}`, `{"total":-1,"tags":[1,2],"x":1}`,
+			[]FieldError{
+				{"data.id", "is required"},
+				{"data.tags", "must have at most 1 items"},
+				{"data.total", "must be >= 0"},
+				{"data.x", "is not an allowed property"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			var data map[string]any
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Validate(data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate(%s) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNilData(t *testing.T) {
+	s, err := Compile([]byte(`{"type":"object","required":["a"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldError{{"data.a", "is required"}}
+	if got := s.Validate(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate(nil) = %v, want %v", got, want)
+	}
+}
+
+func TestValidateGoNumbers(t *testing.T) {
+	// Data built in Go rather than decoded from JSON still compares equal.
+	s, err := Compile([]byte(`{"properties":{"a":{"const":3},"b":{"enum":[1,2]},"c":{"type":"integer"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Validate(map[string]any{"a": 3, "b": int64(2), "c": 4}); got != nil {
+		t.Errorf("Validate() = %v, want no errors", got)
+	}
+}
@@ -0,0 +1,110 @@
+// Package signature signs and verifies outbound Nexus webhook payloads.
+//
+// Every delivery carries a header of the form
+//
+//	Nexus-Signature: t=1700000000,v1=5257a869...,v1=9f1c0b2e...
+//
+// where t is the unix time the request was signed and each v1 is the hex
+// HMAC-SHA256 of "<t>.<raw body>" under one of the tenant's active signing
+// secrets. Several v1 values are sent while secrets are being rotated.
+// Receivers should call Verify with the raw request body before parsing it.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderName       = "Nexus-Signature"
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrInvalidHeader  = errors.New("signature: invalid header")
+	ErrTooOld         = errors.New("signature: timestamp outside tolerance")
+	ErrNoValidMatches = errors.New("signature: no matching signature")
+)
+
+// Compute returns the hex encoded v1 signature of payload at timestamp ts.
+func Compute(secret string, ts time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header builds the Nexus-Signature header value for payload, signed with
+// every secret given.
+func Header(ts time.Time, payload []byte, secrets ...string) string {
+	var b strings.Builder
+	b.WriteString("t=")
+	b.WriteString(strconv.FormatInt(ts.Unix(), 10))
+	for _, secret := range secrets {
+		b.WriteString(",v1=")
+		b.WriteString(Compute(secret, ts, payload))
+	}
+	return b.String()
+}
+
+// Verify checks header against payload and secret. Signatures older or newer
+// than tolerance are rejected to limit replay of captured requests; a zero
+// tolerance uses DefaultTolerance.
+func Verify(payload []byte, header, secret string, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	ts, sigs, err := parse(header)
+	if err != nil {
+		return err
+	}
+
+	age := time.Since(ts)
+	if age > tolerance || age < -tolerance {
+		return ErrTooOld
+	}
+
+	expected := []byte(Compute(secret, ts, payload))
+	for _, sig := range sigs {
+		if hmac.Equal(expected, []byte(sig)) {
+			return nil
+		}
+	}
+	return ErrNoValidMatches
+}
+
+func parse(header string) (time.Time, []string, error) {
+	var (
+		ts    time.Time
+		hasTS bool
+		sigs  []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return time.Time{}, nil, ErrInvalidHeader
+		}
+		switch key {
+		case "t":
+			sec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return time.Time{}, nil, ErrInvalidHeader
+			}
+			ts = time.Unix(sec, 0)
+			hasTS = true
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+	if !hasTS || len(sigs) == 0 {
+		return time.Time{}, nil, ErrInvalidHeader
+	}
+	return ts, sigs, nil
+}
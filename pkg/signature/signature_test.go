@@ -0,0 +1,70 @@
+package signature
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testPayload = []byte(`{"id":"evt_1"}`)
+
+func TestVerifyTolerance(t *testing.T) {
+	tests := []struct {
+		name      string
+		age       time.Duration
+		tolerance time.Duration
+		want      error
+	}{
+		{"fresh", 0, time.Minute, nil},
+		{"within tolerance", 50 * time.Second, time.Minute, nil},
+		{"too old", 2 * time.Minute, time.Minute, ErrTooOld},
+		{"slight clock skew", -30 * time.Second, time.Minute, nil},
+		{"too far in the future", -2 * time.Minute, time.Minute, ErrTooOld},
+		{"zero tolerance uses default", DefaultTolerance - time.Minute, 0, nil},
+		{"zero tolerance rejects past default", DefaultTolerance + time.Minute, 0, ErrTooOld},
+		{"negative tolerance uses default", DefaultTolerance - time.Minute, -time.Second, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := Header(time.Now().Add(-tt.age), testPayload, "secret")
+			if err := Verify(testPayload, header, "secret", tt.tolerance); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		want    error
+	}{
+		{"single secret", testPayload, Header(now, testPayload, "secret"), nil},
+		{"rotation, new secret first", testPayload, Header(now, testPayload, "secret", "old"), nil},
+		{"rotation, new secret last", testPayload, Header(now, testPayload, "old", "secret"), nil},
+		{"wrong secret", testPayload, Header(now, testPayload, "other"), ErrNoValidMatches},
+		{"modified body", []byte(`{"id":"evt_2"}`), Header(now, testPayload, "secret"), ErrNoValidMatches},
+		{"signature for another timestamp", testPayload,
+			"t=" + ts + ",v1=" + Compute("secret", now.Add(-time.Second), testPayload), ErrNoValidMatches},
+		{"unknown scheme ignored", testPayload, Header(now, testPayload, "secret") + ",v0=abc", nil},
+		{"spaces after commas", testPayload, "t=" + ts + ", v1=" + Compute("secret", now, testPayload), nil},
+		{"missing timestamp", testPayload, "v1=" + Compute("secret", now, testPayload), ErrInvalidHeader},
+		{"missing signature", testPayload, "t=" + ts, ErrInvalidHeader},
+		{"bad timestamp", testPayload, "t=soon,v1=abc", ErrInvalidHeader},
+		{"part without equals", testPayload, "t=" + ts + ",v1", ErrInvalidHeader},
+		{"empty", testPayload, "", ErrInvalidHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.payload, tt.header, "secret", time.Minute); !errors.Is(err, tt.want) {
+				t.Errorf("Verify(%q) error = %v, want %v", tt.header, err, tt.want)
+			}
+		})
+	}
+}
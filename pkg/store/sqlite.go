@@ -3,8 +3,11 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/control"
 )
 
 func Open(dsn string) (*sql.DB, error) {
@@ -20,6 +23,20 @@ func Open(dsn string) (*sql.DB, error) {
 	return db, nil
 }
 
+// apiKeysTable stores keys by a salted hash; the secret itself is only
+// shown once, when the key is created.
+const apiKeysTable = `CREATE TABLE IF NOT EXISTS api_keys (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			key_prefix TEXT NOT NULL,     -- public part of the secret used for lookup
+			secret_hash TEXT NOT NULL,    -- hex HMAC-SHA256 of the secret keyed by secret_salt
+			secret_salt TEXT NOT NULL,
+			label TEXT,
+			allowed_channels TEXT,        -- JSON array of channel names/patterns
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`
+
 func Migrate(db *sql.DB) error {
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS tenants (
@@ -27,23 +44,145 @@ func Migrate(db *sql.DB) error {
 			name TEXT NOT NULL,
 			created_at TIMESTAMP NOT NULL
 		);`,
-		`CREATE TABLE IF NOT EXISTS api_keys (
+		apiKeysTable,
+		`CREATE TABLE IF NOT EXISTS routes (
 			id TEXT PRIMARY KEY,
 			tenant_id TEXT NOT NULL,
-			secret TEXT NOT NULL UNIQUE,
-			label TEXT,
+			match_type TEXT NOT NULL,    -- EXACT, PREFIX, GLOB or REGEX
+			match_value TEXT NOT NULL,   -- event type, prefix or pattern
+			target_channel TEXT NOT NULL,
 			created_at TIMESTAMP NOT NULL,
 			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
 		);`,
-		`CREATE TABLE IF NOT EXISTS routes (
+		`CREATE TABLE IF NOT EXISTS events (
 			id TEXT PRIMARY KEY,
 			tenant_id TEXT NOT NULL,
-			match_type TEXT NOT NULL,    -- EXACT or PREFIX
-			match_value TEXT NOT NULL,   -- event type or prefix
-			target_channel TEXT NOT NULL,
+			type TEXT NOT NULL,
+			source TEXT NOT NULL,        -- JSON encoded SourceInfo
+			data TEXT,                   -- JSON encoded event data
+			metadata TEXT,               -- JSON encoded event metadata
+			ingested_at TIMESTAMP NOT NULL,
+			delivery_state TEXT NOT NULL, -- PENDING, RETRYING, DELIVERED, FAILED or DROPPED
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_events_tenant_ingested ON events(tenant_id, ingested_at);`,
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			tenant_id TEXT NOT NULL,
+			key TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY(tenant_id, key),
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS client_event_ids (
+			tenant_id TEXT NOT NULL,
+			source TEXT NOT NULL,           -- CloudEvents source, empty for native events
+			client_event_id TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY(tenant_id, source, client_event_id),
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS channel_sequences (
+			channel TEXT PRIMARY KEY,
+			seq INTEGER NOT NULL          -- last sequence number assigned on the channel
+		);`,
+		`CREATE TABLE IF NOT EXISTS channel_messages (
+			channel TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			payload BLOB NOT NULL,        -- broadcast payload as delivered to subscribers
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY(channel, seq)
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			endpoint_id TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			state TEXT NOT NULL,          -- PENDING, RETRYING, DELIVERED or FAILED
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+			FOREIGN KEY(endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(state, next_attempt_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(event_id);`,
+		`CREATE TABLE IF NOT EXISTS dead_letters (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			delivery_id TEXT NOT NULL,    -- failed webhook_deliveries row
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			endpoint_id TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			last_error TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_dead_letters_tenant ON dead_letters(tenant_id, created_at);`,
+		`CREATE TABLE IF NOT EXISTS signing_secrets (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP,         -- set when rotated out; NULL while current
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		// Tenants created before signing existed get an initial secret.
+		`INSERT INTO signing_secrets (id, tenant_id, secret, created_at)
+		 SELECT lower(hex(randomblob(16))), t.id, 'whsec_' || lower(hex(randomblob(32))), CURRENT_TIMESTAMP
+		   FROM tenants t
+		  WHERE NOT EXISTS (SELECT 1 FROM signing_secrets s WHERE s.tenant_id = t.id);`,
+		`CREATE TABLE IF NOT EXISTS route_versions (
+			tenant_id TEXT PRIMARY KEY,
+			version INTEGER NOT NULL,    -- bumped on every change to the tenant's routes
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS redaction_policies (
+			tenant_id TEXT PRIMARY KEY,
+			policy TEXT NOT NULL,        -- JSON redact.Policy
+			salt TEXT NOT NULL,          -- HMAC key for hash-mode redaction
+			updated_at TIMESTAMP NOT NULL,
 			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
 		);`,
+		`CREATE TABLE IF NOT EXISTS event_schemas (
+			tenant_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			schema TEXT NOT NULL,         -- JSON Schema for the event's data
+			active INTEGER NOT NULL DEFAULT 0, -- at most one active version per event type
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY(tenant_id, event_type, version),
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS admin_tokens (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			role TEXT NOT NULL,           -- superadmin, tenant-admin or read-only
+			tenant_id TEXT,               -- NULL for tokens covering every tenant
+			key_prefix TEXT NOT NULL,     -- public part of the secret used for lookup
+			secret_hash TEXT NOT NULL,    -- hex HMAC-SHA256 of the secret keyed by secret_salt
+			secret_salt TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			FOREIGN KEY(tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_tokens_prefix ON admin_tokens(key_prefix);`,
 	}
 
 	for _, s := range stmts {
@@ -52,5 +191,220 @@ func Migrate(db *sql.DB) error {
 		}
 	}
 
+	if err := migrateAPIKeySecrets(db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(key_prefix);`); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	// Columns added after the initial schema; CREATE TABLE IF NOT EXISTS
+	// does not touch tables that already exist in older databases.
+	columns := []struct {
+		table, name, def string
+	}{
+		{"api_keys", "expires_at", "TIMESTAMP"},
+		{"api_keys", "revoked_at", "TIMESTAMP"},
+		{"api_keys", "last_used_at", "TIMESTAMP"}, // flushed in batches by the gateway
+		// Keys from before scopes keep publishing and subscribing.
+		{"api_keys", "scopes", `TEXT NOT NULL DEFAULT '["events:publish","channels:subscribe"]'`},
+		{"api_keys", "allowed_event_types", "TEXT"}, // JSON array of event types/patterns
+		{"routes", "filter", "TEXT"},                // JSON routing.Filter on data/metadata
+		{"routes", "enabled", "INTEGER NOT NULL DEFAULT 1"},
+		{"routes", "version", "INTEGER NOT NULL DEFAULT 1"}, // optimistic concurrency token
+		{"routes", "updated_at", "TIMESTAMP"},
+		{"routes", "transform", "TEXT"}, // JSON routing.Transform applied before delivery
+		{"routes", "priority", "INTEGER NOT NULL DEFAULT 0"},
+		{"routes", "stop_processing", "INTEGER NOT NULL DEFAULT 0"},
+		{"tenants", "fallback_mode", "TEXT NOT NULL DEFAULT 'default'"}, // default, channel or drop
+		{"tenants", "fallback_channel", "TEXT"},
+		{"events", "validation", "TEXT"},                              // JSON events.Validation when a schema applied
+		{"tenants", "schema_mode", "TEXT NOT NULL DEFAULT 'reject'"},  // reject or tag invalid events
+		{"events", "client_event_id", "TEXT"},                         // event ID supplied by the producer
+		{"events", "client_event_source", "TEXT NOT NULL DEFAULT ''"}, // CloudEvents source scoping client_event_id
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.name, c.def); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	if err := migrateClientEventIDs(db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	return nil
+}
+
+// migrateClientEventIDs replaces the unique indexes that kept producer
+// event IDs unique forever with claims in client_event_ids, which expire
+// with the idempotency window. The events the indexes covered become the
+// initial claims.
+func migrateClientEventIDs(db *sql.DB) error {
+	var n int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master
+          WHERE type = 'index' AND name IN ('idx_events_client_id', 'idx_events_client_source_id')`,
+	).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("inspect client event id indexes: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("migrate client event ids: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		`INSERT OR IGNORE INTO client_event_ids (tenant_id, source, client_event_id, event_id, created_at)
+         SELECT tenant_id, client_event_source, client_event_id, id, ingested_at
+           FROM events
+          WHERE client_event_id IS NOT NULL`,
+		`DROP INDEX IF EXISTS idx_events_client_id`,
+		`DROP INDEX IF EXISTS idx_events_client_source_id`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate client event ids: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateAPIKeySecrets rebuilds an api_keys table that still stores
+// plaintext secrets. Each key keeps working: its prefix and hash are
+// computed from the stored secret, which is then dropped.
+func migrateAPIKeySecrets(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	defer tx.Rollback()
+
+	oldCols, err := tableColumns(tx, "api_keys")
+	if err != nil {
+		return err
+	}
+	if !oldCols["secret"] {
+		return nil
+	}
+
+	if _, err := tx.Exec(`ALTER TABLE api_keys RENAME TO api_keys_plaintext`); err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	if _, err := tx.Exec(apiKeysTable); err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	newCols, err := tableColumns(tx, "api_keys")
+	if err != nil {
+		return err
+	}
+	var shared []string
+	for name := range newCols {
+		if oldCols[name] {
+			shared = append(shared, name)
+		}
+	}
+	sort.Strings(shared)
+	cols := strings.Join(shared, ", ")
+
+	rows, err := tx.Query(`SELECT id, secret FROM api_keys_plaintext`)
+	if err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	type legacyKey struct{ id, secret string }
+	var keys []legacyKey
+	for rows.Next() {
+		var k legacyKey
+		if err := rows.Scan(&k.id, &k.secret); err != nil {
+			rows.Close()
+			return fmt.Errorf("hash api keys: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+
+	for _, k := range keys {
+		prefix, ok := control.APIKeyPrefix(k.secret)
+		if !ok {
+			return fmt.Errorf("hash api keys: key %s has an unrecognised secret format", k.id)
+		}
+		salt, err := control.NewAPIKeySalt()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			fmt.Sprintf(`INSERT INTO api_keys (%s, key_prefix, secret_hash, secret_salt)
+			             SELECT %s, ?, ?, ? FROM api_keys_plaintext WHERE id = ?`, cols, cols),
+			prefix, control.HashAPIKeySecret(salt, k.secret), salt, k.id,
+		); err != nil {
+			return fmt.Errorf("hash api keys: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DROP TABLE api_keys_plaintext`); err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	// The dropped table's pages still hold the plaintext until the file
+	// is rebuilt.
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("hash api keys: %w", err)
+	}
+	return nil
+}
+
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func tableColumns(q querier, table string) (map[string]bool, error) {
+	rows, err := q.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("table info %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, fmt.Errorf("scan table info %s: %w", table, err)
+		}
+		cols[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("table info %s: %w", table, err)
+	}
+	return cols, nil
+}
+
+func addColumnIfMissing(db *sql.DB, table, column, def string) error {
+	cols, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	if cols[column] {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, def)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
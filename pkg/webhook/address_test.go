@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"http://10.0.0.5/hook",
+	} {
+		if err := CheckURL(ctx, u, false); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckURL(%q) error = %v, want ErrPrivateAddress", u, err)
+		}
+		if err := CheckURL(ctx, u, true); err != nil {
+			t.Errorf("CheckURL(%q, allowPrivate) error = %v", u, err)
+		}
+	}
+	if err := CheckURL(ctx, "https://93.184.216.34/hook", false); err != nil {
+		t.Errorf("CheckURL(public) error = %v", err)
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	if err := dialControl("tcp4", "169.254.169.254:80", nil); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("dialControl(metadata) error = %v, want ErrPrivateAddress", err)
+	}
+	if err := dialControl("tcp6", "[::1]:443", nil); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("dialControl(loopback) error = %v, want ErrPrivateAddress", err)
+	}
+	if err := dialControl("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("dialControl(public) error = %v", err)
+	}
+}
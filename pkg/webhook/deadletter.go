@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+)
+
+var ErrNoRedriveTarget = errors.New("webhook endpoint of the dead letter no longer exists")
+
+type DeadLetter struct {
+	ID         string
+	TenantID   string
+	DeliveryID string
+	EventID    string
+	EventType  string
+	EndpointID string
+	Payload    []byte
+	LastError  string
+	Attempts   int
+	CreatedAt  time.Time
+}
+
+func insertDeadLetter(ctx context.Context, ex execer, deliveryID string) error {
+	_, err := ex.ExecContext(ctx,
+		`INSERT INTO dead_letters
+                (id, tenant_id, delivery_id, event_id, event_type, endpoint_id, payload, last_error, attempts, created_at)
+         SELECT ?, d.tenant_id, d.id, d.event_id, COALESCE(ev.type, ''), d.endpoint_id, d.payload,
+                d.last_error, d.attempts, ?
+           FROM webhook_deliveries d
+           LEFT JOIN events ev ON ev.id = d.event_id
+          WHERE d.id = ?`,
+		uuid.NewString(), time.Now().UTC(), deliveryID,
+	)
+	if err != nil {
+		return fmt.Errorf("insert dead letter: %w", err)
+	}
+	return nil
+}
+
+const deadLetterColumns = `id, tenant_id, delivery_id, event_id, event_type, endpoint_id, payload, last_error, attempts, created_at`
+
+func scanDeadLetter(sc interface{ Scan(...any) error }) (DeadLetter, error) {
+	var dl DeadLetter
+	err := sc.Scan(
+		&dl.ID, &dl.TenantID, &dl.DeliveryID, &dl.EventID, &dl.EventType, &dl.EndpointID,
+		&dl.Payload, &dl.LastError, &dl.Attempts, &dl.CreatedAt,
+	)
+	return dl, err
+}
+
+func (s *Store) ListDeadLetters(ctx context.Context, tenantID string, limit int) ([]DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+deadLetterColumns+`
+           FROM dead_letters
+          WHERE tenant_id = ?
+          ORDER BY created_at DESC
+          LIMIT ?`,
+		tenantID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var out []DeadLetter
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan dead letter: %w", err)
+		}
+		out = append(out, dl)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetDeadLetter(ctx context.Context, tenantID, id string) (*DeadLetter, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+deadLetterColumns+`
+           FROM dead_letters
+          WHERE tenant_id = ? AND id = ?`,
+		tenantID, id,
+	)
+	dl, err := scanDeadLetter(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get dead letter: %w", err)
+	}
+	return &dl, nil
+}
+
+func (s *Store) DeleteDeadLetter(ctx context.Context, tenantID, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM dead_letters WHERE tenant_id = ? AND id = ?`,
+		tenantID, id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete dead letter: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete dead letter: %w", err)
+	}
+	return n > 0, nil
+}
+
+// OriginalEvent returns the event dl was delivering as it was ingested,
+// before any route transform, or nil if it is no longer stored.
+func (s *Store) OriginalEvent(ctx context.Context, dl DeadLetter) (*events.EventEnvelope, error) {
+	return events.Get(ctx, s.db, dl.TenantID, dl.EventID)
+}
+
+// Redrive queues a fresh delivery of payload to the dead letter's endpoint
+// and removes the dead letter together with the failed delivery it came from.
+func (s *Store) Redrive(ctx context.Context, dl DeadLetter, payload []byte) (*Delivery, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("redrive dead letter: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	d := Delivery{
+		ID:            uuid.NewString(),
+		TenantID:      dl.TenantID,
+		EventID:       dl.EventID,
+		EndpointID:    dl.EndpointID,
+		Payload:       payload,
+		State:         events.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries
+                (id, tenant_id, event_id, endpoint_id, payload, state, attempts, next_attempt_at, created_at, updated_at)
+         SELECT ?, tenant_id, ?, id, ?, ?, 0, ?, ?, ?
+           FROM webhook_endpoints
+          WHERE id = ? AND tenant_id = ?`,
+		d.ID, d.EventID, d.Payload, d.State, now, now, now,
+		dl.EndpointID, dl.TenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("redrive dead letter: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("redrive dead letter: %w", err)
+	} else if n == 0 {
+		return nil, ErrNoRedriveTarget
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE id = ?`, dl.DeliveryID); err != nil {
+		return nil, fmt.Errorf("redrive dead letter: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM dead_letters WHERE id = ?`, dl.ID); err != nil {
+		return nil, fmt.Errorf("redrive dead letter: %w", err)
+	}
+	if err := syncEventState(ctx, tx, dl.EventID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("redrive dead letter: %w", err)
+	}
+	return &d, nil
+}
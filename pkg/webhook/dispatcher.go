@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/logger"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/signature"
+)
+
+type DispatcherConfig struct {
+	MaxAttempts  int
+	Timeout      time.Duration
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	PollInterval time.Duration
+	// Workers bounds the deliveries in flight at once and MaxPerEndpoint
+	// those to a single endpoint, so a slow endpoint cannot hold up the
+	// others.
+	Workers        int
+	MaxPerEndpoint int
+	// AllowPrivateAddresses lets deliveries reach loopback and private
+	// networks, for local development only.
+	AllowPrivateAddresses bool
+}
+
+// SecretSource supplies the secrets a tenant's deliveries are signed with.
+type SecretSource interface {
+	SigningSecrets(ctx context.Context, tenantID string) ([]string, error)
+}
+
+type Dispatcher struct {
+	cfg     DispatcherConfig
+	log     logger.Logger
+	store   *Store
+	secrets SecretSource
+	client  *http.Client
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	inFlight map[string]int // by endpoint ID
+	busy     int
+	// wake is signalled when a delivery finishes so its worker is reused
+	// without waiting for the next poll.
+	wake chan struct{}
+}
+
+func NewDispatcher(cfg DispatcherConfig, log logger.Logger, store *Store, secrets SecretSource) *Dispatcher {
+	return &Dispatcher{
+		cfg:     cfg,
+		log:     log,
+		store:   store,
+		secrets: secrets,
+		client:  newHTTPClient(cfg.Timeout, cfg.AllowPrivateAddresses),
+
+		inFlight: make(map[string]int),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled, then waits for the
+// deliveries in flight.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.log.Info("webhook dispatcher starting",
+		"poll_interval", d.cfg.PollInterval.String(),
+		"workers", d.cfg.Workers,
+		"max_per_endpoint", d.cfg.MaxPerEndpoint,
+	)
+	defer d.wg.Wait()
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			d.log.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// dispatchDue claims as many due deliveries as there are idle workers and
+// starts each at once, so the lease only has to cover one request.
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	d.mu.Lock()
+	free := d.cfg.Workers - d.busy
+	var full []string
+	for endpointID, n := range d.inFlight {
+		if n >= d.cfg.MaxPerEndpoint {
+			full = append(full, endpointID)
+		}
+	}
+	d.mu.Unlock()
+	if free <= 0 {
+		return
+	}
+
+	lease := d.cfg.Timeout + d.cfg.PollInterval
+	due, err := d.store.ClaimDue(ctx, time.Now(), lease, free, d.cfg.MaxPerEndpoint, full)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.log.Error("claim webhook deliveries failed", "err", err)
+		}
+		return
+	}
+
+	// An endpoint that already had deliveries in flight may have been
+	// claimed past its limit; hand the excess back.
+	var excess []Delivery
+	d.mu.Lock()
+	for _, del := range due {
+		if d.inFlight[del.EndpointID] >= d.cfg.MaxPerEndpoint {
+			excess = append(excess, del)
+			continue
+		}
+		d.inFlight[del.EndpointID]++
+		d.busy++
+		d.wg.Add(1)
+		go d.run(ctx, del)
+	}
+	d.mu.Unlock()
+
+	if len(excess) > 0 {
+		if err := d.store.Release(ctx, excess); err != nil && ctx.Err() == nil {
+			d.log.Error("release webhook deliveries failed", "err", err)
+		}
+	}
+}
+
+func (d *Dispatcher) run(ctx context.Context, del Delivery) {
+	defer d.wg.Done()
+	d.attempt(ctx, del)
+
+	d.mu.Lock()
+	d.busy--
+	if d.inFlight[del.EndpointID]--; d.inFlight[del.EndpointID] == 0 {
+		delete(d.inFlight, del.EndpointID)
+	}
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, del Delivery) {
+	err := d.post(ctx, del)
+	if err == nil {
+		if err := d.store.MarkDelivered(ctx, del); err != nil {
+			d.log.Error("mark webhook delivered failed", "err", err, "delivery_id", del.ID)
+		}
+		d.log.Info("webhook_delivered",
+			"delivery_id", del.ID,
+			"event_id", del.EventID,
+			"endpoint_id", del.EndpointID,
+			"attempt", del.Attempts+1,
+		)
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and the delivery is retried.
+		return
+	}
+
+	if del.Attempts+1 >= d.cfg.MaxAttempts {
+		if err := d.store.MarkFailed(ctx, del, err.Error()); err != nil {
+			d.log.Error("mark webhook failed failed", "err", err, "delivery_id", del.ID)
+		}
+		d.log.Warn("webhook_failed",
+			"delivery_id", del.ID,
+			"event_id", del.EventID,
+			"endpoint_id", del.EndpointID,
+			"attempts", del.Attempts+1,
+			"err", err,
+		)
+		return
+	}
+
+	next := time.Now().Add(d.backoff(del.Attempts + 1))
+	if err := d.store.MarkRetrying(ctx, del, next, err.Error()); err != nil {
+		d.log.Error("mark webhook retrying failed", "err", err, "delivery_id", del.ID)
+	}
+	d.log.Warn("webhook_retry_scheduled",
+		"delivery_id", del.ID,
+		"event_id", del.EventID,
+		"endpoint_id", del.EndpointID,
+		"attempt", del.Attempts+1,
+		"next_attempt_at", next.UTC(),
+		"err", err,
+	)
+}
+
+func (d *Dispatcher) post(ctx context.Context, del Delivery) error {
+	secrets, err := d.secrets.SigningSecrets(ctx, del.TenantID)
+	if err != nil {
+		return fmt.Errorf("load signing secrets: %w", err)
+	}
+	if len(secrets) == 0 {
+		return fmt.Errorf("tenant has no active signing secret")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Nexus-Webhooks/1.0")
+	req.Header.Set("Nexus-Event-Id", del.EventID)
+	req.Header.Set("Nexus-Delivery-Id", del.ID)
+	req.Header.Set(signature.HeaderName, signature.Header(time.Now(), del.Payload, secrets...))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff doubles per attempt up to BackoffMax and randomises the upper half
+// of the wait so retries from many failing deliveries spread out.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	ceiling := d.cfg.BackoffMax
+	wait := d.cfg.BackoffBase
+	for i := 1; i < attempt && wait < ceiling; i++ {
+		wait *= 2
+	}
+	if wait > ceiling {
+		wait = ceiling
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
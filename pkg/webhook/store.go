@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tejassathe/Nexus-ProtocolNetwork/pkg/events"
+)
+
+// TargetPrefix marks a route target as a webhook endpoint rather than a
+// realtime channel, e.g. "webhook:<endpoint_id>".
+const TargetPrefix = "webhook:"
+
+var ErrUnknownEndpoint = errors.New("unknown webhook endpoint")
+
+func Target(endpointID string) string {
+	return TargetPrefix + endpointID
+}
+
+func ParseTarget(target string) (string, bool) {
+	if !strings.HasPrefix(target, TargetPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(target, TargetPrefix), true
+}
+
+type Delivery struct {
+	ID            string
+	TenantID      string
+	EventID       string
+	EndpointID    string
+	URL           string
+	Payload       []byte
+	State         string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Enqueue(ctx context.Context, tenantID, eventID, endpointID string, payload []byte) (*Delivery, error) {
+	id := uuid.NewString()
+	now := time.Now().UTC()
+
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries
+                (id, tenant_id, event_id, endpoint_id, payload, state, attempts, next_attempt_at, created_at, updated_at)
+         SELECT ?, tenant_id, ?, id, ?, ?, 0, ?, ?, ?
+           FROM webhook_endpoints
+          WHERE id = ? AND tenant_id = ?`,
+		id, eventID, payload, events.DeliveryPending, now, now, now,
+		endpointID, tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("enqueue webhook delivery: %w", err)
+	} else if n == 0 {
+		return nil, ErrUnknownEndpoint
+	}
+
+	return &Delivery{
+		ID:            id,
+		TenantID:      tenantID,
+		EventID:       eventID,
+		EndpointID:    endpointID,
+		Payload:       payload,
+		State:         events.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// ClaimDue leases up to limit due deliveries by pushing their next attempt
+// out by lease, so a crashed worker's deliveries are picked up again later.
+// It takes at most perEndpoint deliveries for any one endpoint and none for
+// the endpoints in skip.
+func (s *Store) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit, perEndpoint int, skip []string) ([]Delivery, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := []any{events.DeliveryPending, events.DeliveryRetrying, now.UTC()}
+	skipClause := ""
+	if len(skip) > 0 {
+		skipClause = ` AND d.endpoint_id NOT IN (?` + strings.Repeat(`, ?`, len(skip)-1) + `)`
+		for _, id := range skip {
+			args = append(args, id)
+		}
+	}
+	args = append(args, perEndpoint, limit)
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, tenant_id, event_id, endpoint_id, url, payload,
+                state, attempts, next_attempt_at, last_error, created_at, updated_at
+           FROM (SELECT d.*, e.url,
+                        ROW_NUMBER() OVER (PARTITION BY d.endpoint_id ORDER BY d.next_attempt_at) AS n
+                   FROM webhook_deliveries d
+                   JOIN webhook_endpoints e ON e.id = d.endpoint_id
+                  WHERE d.state IN (?, ?) AND d.next_attempt_at <= ?`+skipClause+`)
+          WHERE n <= ?
+          ORDER BY next_attempt_at ASC
+          LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	var out []Delivery
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(
+			&d.ID, &d.TenantID, &d.EventID, &d.EndpointID, &d.URL, &d.Payload,
+			&d.State, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.UpdatedAt,
+		); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	rows.Close()
+
+	leaseUntil := now.Add(lease).UTC()
+	for _, d := range out {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?`,
+			leaseUntil, d.ID,
+		); err != nil {
+			return nil, fmt.Errorf("lease webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	return out, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Release hands claimed deliveries back before their lease runs out, due
+// again at the time they were claimed.
+func (s *Store) Release(ctx context.Context, ds []Delivery) error {
+	for _, d := range ds {
+		if _, err := s.db.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?`,
+			d.NextAttemptAt.UTC(), d.ID,
+		); err != nil {
+			return fmt.Errorf("release webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) MarkDelivered(ctx context.Context, d Delivery) error {
+	return s.update(ctx, s.db, d, events.DeliveryDelivered, d.Attempts+1, time.Now().UTC(), "")
+}
+
+func (s *Store) MarkRetrying(ctx context.Context, d Delivery, nextAttempt time.Time, lastErr string) error {
+	return s.update(ctx, s.db, d, events.DeliveryRetrying, d.Attempts+1, nextAttempt.UTC(), lastErr)
+}
+
+// MarkFailed gives up on a delivery and moves it to the tenant's dead-letter
+// queue in the same transaction.
+func (s *Store) MarkFailed(ctx context.Context, d Delivery, lastErr string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("fail webhook delivery: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.update(ctx, tx, d, events.DeliveryFailed, d.Attempts+1, time.Now().UTC(), lastErr); err != nil {
+		return err
+	}
+	if err := insertDeadLetter(ctx, tx, d.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("fail webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) update(ctx context.Context, ex execer, d Delivery, state string, attempts int, nextAttempt time.Time, lastErr string) error {
+	_, err := ex.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+            SET state = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ?
+          WHERE id = ?`,
+		state, attempts, nextAttempt, lastErr, time.Now().UTC(), d.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+	return syncEventState(ctx, ex, d.EventID)
+}
+
+// syncEventState rolls the state of every delivery of an event up into
+// events.delivery_state: any failure wins, then any outstanding retry, and
+// the event only counts as delivered once every endpoint has accepted it.
+func syncEventState(ctx context.Context, ex execer, eventID string) error {
+	_, err := ex.ExecContext(ctx,
+		`UPDATE events
+            SET delivery_state = (
+                SELECT CASE
+                         WHEN SUM(state = ?) > 0 THEN ?
+                         WHEN SUM(state = ?) = COUNT(*) THEN ?
+                         WHEN SUM(attempts) > 0 THEN ?
+                         ELSE ?
+                       END
+                  FROM webhook_deliveries
+                 WHERE event_id = ?)
+          WHERE id = ?`,
+		events.DeliveryFailed, events.DeliveryFailed,
+		events.DeliveryDelivered, events.DeliveryDelivered,
+		events.DeliveryRetrying,
+		events.DeliveryPending,
+		eventID, eventID,
+	)
+	if err != nil {
+		return fmt.Errorf("sync event delivery state: %w", err)
+	}
+	return nil
+}